@@ -26,7 +26,8 @@ codec cache (conn/pool specific) mapping:
 (Type ID, Go Type Ref) -> Codec
 
 type id cache (conn/pool) mapping:
-(Query, Expected Cardinality, IO Format) -> (In Type ID, Out Type ID)
+(Query, Expected Cardinality, IO Format, Implicit Headers)
+	-> (In Type ID, Out Type ID)
 
 Optimistic execute flow:
 1. check type id cache for (eql, expCard, format).
@@ -46,6 +47,7 @@ import (
 	"github.com/edgedb/edgedb-go/internal/cache"
 	"github.com/edgedb/edgedb-go/internal/codecs"
 	"github.com/edgedb/edgedb-go/internal/descriptor"
+	"github.com/edgedb/edgedb-go/internal/header"
 )
 
 var descCache = cache.New(1_000)
@@ -63,6 +65,9 @@ type codecPair struct {
 type descPair struct {
 	in  descriptor.Descriptor
 	out descriptor.Descriptor
+
+	// card is the result cardinality reported by the server.
+	card uint8
 }
 
 type idPair struct {
@@ -70,20 +75,30 @@ type idPair struct {
 	out UUID
 }
 
+// queryKey identifies a query's type ids.
+// The implicit headers change the shape of the result,
+// so they are part of the key.
 type queryKey struct {
 	cmd     string
 	fmt     uint8
 	expCard uint8
+
+	implicitLimit    uint64
+	compilationFlags uint64
 }
 
-func (c *baseConn) getTypeIDs(q *gfQuery) (idPair, bool) {
-	key := queryKey{
-		cmd:     q.cmd,
-		fmt:     q.fmt,
-		expCard: q.expCard,
+func newQueryKey(q *gfQuery) queryKey {
+	return queryKey{
+		cmd:              q.cmd,
+		fmt:              q.fmt,
+		expCard:          q.expCard,
+		implicitLimit:    header.ImplicitLimitOf(q.headers),
+		compilationFlags: header.CompilationFlags(q.headers),
 	}
+}
 
-	if val, ok := c.typeIDCache.Get(key); ok {
+func (c *baseConn) getTypeIDs(q *gfQuery) (idPair, bool) {
+	if val, ok := c.typeIDCache.Get(newQueryKey(q)); ok {
 		return val.(idPair), true
 	}
 
@@ -91,10 +106,5 @@ func (c *baseConn) getTypeIDs(q *gfQuery) (idPair, bool) {
 }
 
 func (c *baseConn) putTypeIDs(q *gfQuery, ids idPair) {
-	key := queryKey{
-		cmd:     q.cmd,
-		fmt:     q.fmt,
-		expCard: q.expCard,
-	}
-	c.typeIDCache.Put(key, ids)
+	c.typeIDCache.Put(newQueryKey(q), ids)
 }
@@ -0,0 +1,372 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedb
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/edgedb/edgedb-go/internal/buff"
+	"github.com/edgedb/edgedb-go/internal/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stallingServer is a fake server that answers every script
+// with CommandComplete except for the script "stall"
+// which is not answered until unstall is closed
+// or the connection's command is cancelled.
+type stallingServer struct {
+	ln       net.Listener
+	accepted int32
+	cancels  int32
+	unstall  chan struct{}
+
+	mu    sync.Mutex
+	conns []net.Conn
+
+	// cancelled is closed when a connection's command is cancelled.
+	cancelled map[[32]byte]chan struct{}
+
+	// ignoreCancel makes cancel requests have no effect.
+	ignoreCancel bool
+}
+
+func startStallingServer(t *testing.T) *stallingServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+
+	s := &stallingServer{ln: ln, unstall: make(chan struct{})}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			s.mu.Lock()
+			s.conns = append(s.conns, conn)
+			s.mu.Unlock()
+			go s.serve(conn)
+		}
+	}()
+
+	return s
+}
+
+func (s *stallingServer) options() Options {
+	addr := s.ln.Addr().(*net.TCPAddr)
+	return Options{
+		Hosts:    []string{addr.IP.String()},
+		Ports:    []int{addr.Port},
+		User:     "edgedb",
+		Database: "edgedb",
+		MinConns: 1,
+		MaxConns: 1,
+	}
+}
+
+func (s *stallingServer) close() {
+	_ = s.ln.Close()
+}
+
+// ignoreCancels makes the server finish stalled commands
+// only when unstall is closed.
+func (s *stallingServer) ignoreCancels() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ignoreCancel = true
+}
+
+// newKey returns the key data for a new connection
+// and the channel that is closed when it is cancelled.
+func (s *stallingServer) newKey() ([32]byte, chan struct{}) {
+	n := atomic.AddInt32(&s.accepted, 1)
+
+	var key [32]byte
+	binary.BigEndian.PutUint32(key[:], uint32(n))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancelled == nil {
+		s.cancelled = make(map[[32]byte]chan struct{})
+	}
+
+	cancelled := make(chan struct{})
+	s.cancelled[key] = cancelled
+	return key, cancelled
+}
+
+// cancel cancels the command of the connection identified by key.
+func (s *stallingServer) cancel(key []byte) {
+	atomic.AddInt32(&s.cancels, 1)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var k [32]byte
+	copy(k[:], key)
+
+	cancelled, ok := s.cancelled[k]
+	if !ok || s.ignoreCancel {
+		return
+	}
+
+	close(cancelled)
+	delete(s.cancelled, k)
+}
+
+// stall waits until unstall is closed or the command is cancelled.
+// It returns true if the command was cancelled.
+func (s *stallingServer) stall(cancelled <-chan struct{}) bool {
+	select {
+	case <-s.unstall:
+		return false
+	case <-cancelled:
+		return true
+	}
+}
+
+// dropConns closes all of the accepted connections.
+func (s *stallingServer) dropConns() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, conn := range s.conns {
+		_ = conn.Close()
+	}
+
+	s.conns = nil
+}
+
+func (s *stallingServer) serve(conn net.Conn) {
+	defer conn.Close() // nolint:errcheck
+
+	var cancelled chan struct{}
+
+	for {
+		mType, payload, err := readClientMessage(conn)
+		if err != nil {
+			return
+		}
+
+		w := buff.NewWriter(nil)
+
+		switch mType {
+		case message.ClientHandshake:
+			w.BeginMessage(message.ServerHandshake)
+			w.PushUint16(0)  // major version
+			w.PushUint16(10) // minor version
+			w.PushUint16(0)  // no extensions
+			w.EndMessage()
+
+			w.BeginMessage(message.Authentication)
+			w.PushUint32(0) // auth status
+			w.EndMessage()
+
+			var key [32]byte
+			key, cancelled = s.newKey()
+			w.BeginMessage(message.ServerKeyData)
+			w.PushBytes(key[:])
+			w.EndMessage()
+		case message.CancelRequest:
+			s.cancel(payload)
+			return
+		case message.ExecuteScript:
+			r := buff.SimpleReader(payload)
+			ignoreHeaders(r)
+			if r.PopString() == "stall" && s.stall(cancelled) {
+				w.BeginMessage(message.ErrorResponse)
+				w.PushUint8(0x78)           // severity
+				w.PushUint32(0x04_00_00_00) // QueryError
+				w.PushString("the query was cancelled")
+				w.PushUint16(0) // no headers
+				w.EndMessage()
+				break
+			}
+
+			w.BeginMessage(message.CommandComplete)
+			w.PushUint16(0) // no headers
+			w.PushString("SELECT")
+			w.EndMessage()
+		case message.Terminate:
+			return
+		default:
+			return
+		}
+
+		w.BeginMessage(message.ReadyForCommand)
+		w.PushUint16(0) // no headers
+		w.PushUint8('I')
+		w.EndMessage()
+
+		if w.Send(conn) != nil {
+			return
+		}
+	}
+}
+
+func readClientMessage(conn net.Conn) (uint8, []byte, error) {
+	head := make([]byte, 5)
+	if _, err := io.ReadFull(conn, head); err != nil {
+		return 0, nil, err
+	}
+
+	payload := make([]byte, binary.BigEndian.Uint32(head[1:])-4)
+	if _, err := io.ReadFull(conn, payload); err != nil {
+		return 0, nil, err
+	}
+
+	return head[0], payload, nil
+}
+
+func TestCancelReturnsConnectionToPool(t *testing.T) {
+	server := startStallingServer(t)
+	defer server.close()
+
+	p, err := Connect(context.Background(), server.options())
+	require.Nil(t, err)
+	defer p.Close() // nolint:errcheck
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		50*time.Millisecond,
+	)
+	defer cancel()
+
+	start := time.Now()
+	err = p.Execute(ctx, "stall")
+	require.True(t, errors.Is(err, context.DeadlineExceeded), err)
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+
+	close(server.unstall)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.Nil(t, p.Execute(ctx, "SELECT 1"))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&server.accepted))
+}
+
+func TestCancelInterruptsRunningCommand(t *testing.T) {
+	server := startStallingServer(t)
+	defer server.close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, err := ConnectOne(ctx, server.options())
+	require.Nil(t, err)
+	defer conn.Close() // nolint:errcheck
+	defer close(server.unstall)
+
+	stallCtx, stallCancel := context.WithCancel(ctx)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		stallCancel()
+	}()
+
+	err = conn.Execute(stallCtx, "stall")
+	require.True(t, errors.Is(err, context.Canceled), err)
+
+	// the server stops the command without it being unstalled.
+	waitCtx, waitCancel := context.WithTimeout(ctx, time.Second)
+	defer waitCancel()
+
+	require.Nil(t, conn.Execute(waitCtx, "SELECT 1"))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&server.cancels))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&server.accepted))
+}
+
+func TestCancelConnDrainsInBackground(t *testing.T) {
+	server := startStallingServer(t)
+	defer server.close()
+	server.ignoreCancels()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, err := ConnectOne(ctx, server.options())
+	require.Nil(t, err)
+	defer conn.Close() // nolint:errcheck
+
+	stallCtx, stallCancel := context.WithCancel(ctx)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		stallCancel()
+	}()
+
+	err = conn.Execute(stallCtx, "stall")
+	require.True(t, errors.Is(err, context.Canceled), err)
+
+	// the connection can not be used until the server is done.
+	waitCtx, waitCancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer waitCancel()
+
+	err = conn.Execute(waitCtx, "SELECT 1")
+	require.True(t, errors.Is(err, context.DeadlineExceeded), err)
+
+	close(server.unstall)
+
+	for i := 0; i < 3; i++ {
+		require.Nil(t, conn.Execute(ctx, "SELECT "+strconv.Itoa(i)))
+	}
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&server.accepted))
+}
+
+func TestCancelConnClosedWhileDraining(t *testing.T) {
+	server := startStallingServer(t)
+	defer server.close()
+	server.ignoreCancels()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, err := ConnectOne(ctx, server.options())
+	require.Nil(t, err)
+	defer conn.Close() // nolint:errcheck
+
+	stallCtx, stallCancel := context.WithCancel(ctx)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		stallCancel()
+	}()
+
+	err = conn.Execute(stallCtx, "stall")
+	require.True(t, errors.Is(err, context.Canceled), err)
+
+	// the server goes away before the command is finished.
+	server.dropConns()
+
+	err = conn.Execute(ctx, "SELECT 1")
+	var edbErr Error
+	require.True(t, errors.As(err, &edbErr), err)
+	assert.True(t, edbErr.Category(ClientConnectionClosedError), err)
+	assert.EqualError(t, err, "edgedb.ClientConnectionClosedError: "+
+		"the connection was closed while draining a cancelled query")
+}
@@ -17,6 +17,7 @@
 package edgedb
 
 import (
+	"context"
 	"fmt"
 	"sync"
 
@@ -27,7 +28,11 @@ import (
 
 var (
 	protocolVersionMin = version{0, 9}
-	protocolVersionMax = version{0, 10}
+	protocolVersionMax = version{1, 0}
+
+	// protocolVersion1p0 introduced the Parse and Execute messages
+	// and session state.
+	protocolVersion1p0 = version{1, 0}
 )
 
 type version struct {
@@ -39,7 +44,7 @@ func (v version) gt(other version) bool {
 	switch {
 	case v.major > other.major:
 		return true
-	case v.major < other.minor:
+	case v.major < other.major:
 		return false
 	default:
 		return v.minor > other.minor
@@ -58,14 +63,18 @@ func (v version) lt(other version) bool {
 	switch {
 	case v.major < other.major:
 		return true
-	case v.major > other.minor:
+	case v.major > other.major:
 		return false
 	default:
 		return v.minor < other.minor
 	}
 }
 
-func (c *baseConn) connect(r *buff.Reader, cfg *connConfig) error {
+func (c *baseConn) connect(
+	ctx context.Context,
+	r *buff.Reader,
+	cfg *connConfig,
+) error {
 	w := buff.NewWriter(c.writeMemory[:0])
 	w.BeginMessage(message.ClientHandshake)
 	w.PushUint16(protocolVersionMax.major)
@@ -82,6 +91,11 @@ func (c *baseConn) connect(r *buff.Reader, cfg *connConfig) error {
 		return &clientConnectionError{err: err}
 	}
 
+	// The server only sends ServerHandshake
+	// if it does not support the requested version.
+	c.protocolVersion = protocolVersionMax
+	c.explicitIDs = true
+
 	var (
 		err  error
 		once sync.Once
@@ -110,9 +124,7 @@ func (c *baseConn) connect(r *buff.Reader, cfg *connConfig) error {
 			}
 
 			c.protocolVersion = protocolVersion
-			if protocolVersion.gte(version{0, 10}) {
-				c.explicitIDs = true
-			}
+			c.explicitIDs = protocolVersion.gte(version{0, 10})
 
 			n := r.PopUint16()
 			for i := uint16(0); i < n; i++ {
@@ -120,7 +132,7 @@ func (c *baseConn) connect(r *buff.Reader, cfg *connConfig) error {
 				ignoreHeaders(r)
 			}
 		case message.ServerKeyData:
-			r.DiscardMessage() // key data
+			c.popKeyData(r)
 		case message.ReadyForCommand:
 			ignoreHeaders(r)
 			r.Discard(1) // transaction state
@@ -136,7 +148,7 @@ func (c *baseConn) connect(r *buff.Reader, cfg *connConfig) error {
 				r.PopBytes()
 			}
 
-			if e := c.authenticate(r, cfg); e != nil {
+			if e := c.authenticate(ctx, r, cfg); e != nil {
 				return e
 			}
 
@@ -159,8 +171,23 @@ func (c *baseConn) connect(r *buff.Reader, cfg *connConfig) error {
 	return err
 }
 
-func (c *baseConn) authenticate(r *buff.Reader, cfg *connConfig) error {
-	client, err := scram.SHA256.NewClient(cfg.user, cfg.password, "")
+func (c *baseConn) authenticate(
+	ctx context.Context,
+	r *buff.Reader,
+	cfg *connConfig,
+) error {
+	password := cfg.password
+	if cfg.passwordProvider != nil {
+		var err error
+		password, err = cfg.passwordProvider(ctx)
+		if err != nil {
+			return &authenticationError{
+				err: fmt.Errorf("password provider failed: %w", err),
+			}
+		}
+	}
+
+	client, err := cfg.scramClients.get(cfg.user, password)
 	if err != nil {
 		return &authenticationError{msg: err.Error()}
 	}
@@ -252,7 +279,7 @@ func (c *baseConn) authenticate(r *buff.Reader, cfg *connConfig) error {
 				)}
 			}
 		case message.ServerKeyData:
-			r.DiscardMessage() // key data
+			c.popKeyData(r)
 		case message.ReadyForCommand:
 			ignoreHeaders(r)
 			r.Discard(1) // transaction state
@@ -275,6 +302,12 @@ func (c *baseConn) authenticate(r *buff.Reader, cfg *connConfig) error {
 	return err
 }
 
+// popKeyData stores the key data that identifies this connection
+// in cancel requests.
+func (c *baseConn) popKeyData(r *buff.Reader) {
+	copy(c.keyData[:], r.PopSlice(uint32(len(c.keyData))).Buf)
+}
+
 func (c *baseConn) terminate() error {
 	w := buff.NewWriter(c.writeMemory[:0])
 	w.BeginMessage(message.Terminate)
@@ -286,3 +319,40 @@ func (c *baseConn) terminate() error {
 
 	return nil
 }
+
+// scramClientCache shares a SCRAM client between the connections of a pool.
+// The client caches the keys derived from the password
+// for each salt and iteration count, so that connections
+// do not repeat the expensive key derivation.
+type scramClientCache struct {
+	mu       sync.Mutex
+	user     string
+	password string
+	client   *scram.Client
+}
+
+// get returns the client for user and password.
+// The client and its keys are replaced if the password changed.
+// A nil cache always returns a new client.
+func (c *scramClientCache) get(user, password string) (*scram.Client, error) {
+	if c == nil {
+		return scram.SHA256.NewClient(user, password, "")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.client != nil && c.user == user && c.password == password {
+		return c.client, nil
+	}
+
+	client, err := scram.SHA256.NewClient(user, password, "")
+	if err != nil {
+		return nil, err
+	}
+
+	c.user = user
+	c.password = password
+	c.client = client
+	return client, nil
+}
@@ -18,9 +18,12 @@ package edgedb
 
 import (
 	"context"
+	"errors"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/edgedb/edgedb-go/edgedbtest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -44,3 +47,157 @@ func TestAuth(t *testing.T) {
 	require.Nil(t, err)
 	assert.Equal(t, "It worked!", result)
 }
+
+func TestPasswordProvider(t *testing.T) {
+	server, err := edgedbtest.NewServer()
+	require.Nil(t, err)
+	defer server.Close() // nolint:errcheck
+
+	server.SetPassword("edgedb", "first")
+	server.Handle("SELECT 1", edgedbtest.Response{
+		Type: edgedbtest.Int64,
+		Rows: []interface{}{int64(1)},
+	})
+
+	var (
+		mu       sync.Mutex
+		password = "first"
+		calls    = 0
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p, err := Connect(ctx, Options{
+		Hosts:    []string{server.Host()},
+		Ports:    []int{server.Port()},
+		User:     "edgedb",
+		Password: "ignored",
+		Database: "edgedb",
+		MinConns: 1,
+		MaxConns: 2,
+		PasswordProvider: func(ctx context.Context) (string, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			calls++
+			return password, nil
+		},
+	})
+	require.Nil(t, err)
+	defer p.Close() // nolint:errcheck
+
+	// rotate the password while the pool is open.
+	server.SetPassword("edgedb", "second")
+	mu.Lock()
+	password = "second"
+	mu.Unlock()
+
+	first, err := p.Acquire(ctx)
+	require.Nil(t, err)
+	defer first.Release() // nolint:errcheck
+
+	// the second connection authenticates with the new password.
+	second, err := p.Acquire(ctx)
+	require.Nil(t, err)
+	defer second.Release() // nolint:errcheck
+
+	var result int64
+	require.Nil(t, second.QueryOne(ctx, "SELECT 1", &result))
+	assert.Equal(t, int64(1), result)
+
+	mu.Lock()
+	assert.Equal(t, 2, calls)
+	mu.Unlock()
+}
+
+func TestPasswordProviderError(t *testing.T) {
+	server, err := edgedbtest.NewServer()
+	require.Nil(t, err)
+	defer server.Close() // nolint:errcheck
+
+	server.SetPassword("edgedb", "secret")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	vaultErr := errors.New("vault is sealed")
+	_, err = ConnectOne(ctx, Options{
+		Hosts:    []string{server.Host()},
+		Ports:    []int{server.Port()},
+		User:     "edgedb",
+		Database: "edgedb",
+		PasswordProvider: func(ctx context.Context) (string, error) {
+			return "", vaultErr
+		},
+	})
+
+	var edbErr Error
+	require.True(t, errors.As(err, &edbErr), err)
+	assert.True(t, edbErr.Category(AuthenticationError), err)
+	assert.True(t, errors.Is(err, vaultErr), err)
+	assert.EqualError(
+		t,
+		err,
+		"edgedb.AuthenticationError: "+
+			"password provider failed: vault is sealed",
+	)
+}
+
+func TestScramClientCache(t *testing.T) {
+	cache := &scramClientCache{}
+
+	first, err := cache.get("edgedb", "secret")
+	require.Nil(t, err)
+
+	same, err := cache.get("edgedb", "secret")
+	require.Nil(t, err)
+	assert.Same(t, first, same)
+
+	// the derived keys are discarded when the password changes.
+	rotated, err := cache.get("edgedb", "rotated")
+	require.Nil(t, err)
+	assert.NotSame(t, first, rotated)
+
+	other, err := cache.get("admin", "rotated")
+	require.Nil(t, err)
+	assert.NotSame(t, rotated, other)
+
+	var noCache *scramClientCache
+	a, err := noCache.get("edgedb", "secret")
+	require.Nil(t, err)
+	b, err := noCache.get("edgedb", "secret")
+	require.Nil(t, err)
+	assert.NotSame(t, a, b)
+}
+
+func TestPoolSharesScramClient(t *testing.T) {
+	server, err := edgedbtest.NewServer()
+	require.Nil(t, err)
+	defer server.Close() // nolint:errcheck
+
+	server.SetPassword("edgedb", "secret")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p, err := Connect(ctx, Options{
+		Hosts:    []string{server.Host()},
+		Ports:    []int{server.Port()},
+		User:     "edgedb",
+		Password: "secret",
+		Database: "edgedb",
+		MinConns: 3,
+		MaxConns: 3,
+	})
+	require.Nil(t, err)
+	defer p.Close() // nolint:errcheck
+
+	// the connections authenticated with the pool's client.
+	cached := p.cfg.scramClients.client
+	require.NotNil(t, cached)
+
+	client, err := p.cfg.scramClients.get("edgedb", "secret")
+	require.Nil(t, err)
+	assert.Same(t, cached, client)
+}
@@ -18,7 +18,9 @@ package edgedb
 
 import (
 	"crypto/sha1"
+	"crypto/tls"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/url"
 	"os"
@@ -37,10 +39,32 @@ type connConfig struct {
 	addrs              []*dialArgs
 	user               string
 	password           string
+	passwordProvider   PasswordProvider
 	database           string
 	connectTimeout     time.Duration
 	waitUntilAvailable time.Duration
 	serverSettings     map[string]string
+	onServerMessage    ServerMessageHandler
+
+	// dialer is nil if the default dialer is used.
+	dialer          DialFunc
+	keepAlive       time.Duration
+	readBufferSize  int
+	writeBufferSize int
+
+	// transcript is nil if messages are not recorded.
+	transcript io.Writer
+
+	// scramClients is nil if derived keys are not cached.
+	scramClients *scramClientCache
+
+	// tlsConfig is the user supplied TLS configuration.
+	// If it is nil tlsSecurity and tlsCAData are used instead.
+	tlsConfig *tls.Config
+
+	// tlsSecurity is empty when TLS is not used.
+	tlsSecurity TLSSecurityMode
+	tlsCAData   []byte
 }
 
 type dialArgs struct {
@@ -195,6 +219,9 @@ func parseConnectDSNAndArgs(
 	user := opts.User
 	password := opts.Password
 	database := opts.Database
+	tlsCAFile := opts.TLSCAFile
+	tlsSecurity := opts.TLSSecurity
+	var tlsCAData []byte
 
 	serverSettings := make(map[string]string, len(opts.ServerSettings))
 	for k, v := range opts.ServerSettings {
@@ -318,6 +345,14 @@ func parseConnectDSNAndArgs(
 				password = val
 			}
 
+			if val := pop(query, "tls_ca_file"); tlsCAFile == "" {
+				tlsCAFile = val
+			}
+
+			if val := pop(query, "tls_security"); tlsSecurity == "" {
+				tlsSecurity = TLSSecurityMode(val)
+			}
+
 			for k, v := range query {
 				serverSettings[k] = v
 			}
@@ -365,6 +400,18 @@ func parseConnectDSNAndArgs(
 		if database == "" {
 			database = creds.database
 		}
+
+		if tlsCAFile == "" {
+			tlsCAData = creds.certData
+		}
+
+		if tlsSecurity == "" && creds.verifyHostname != nil {
+			if *creds.verifyHostname {
+				tlsSecurity = TLSModeStrict
+			} else {
+				tlsSecurity = TLSModeNoHostVerification
+			}
+		}
 	}
 
 	var err error
@@ -443,6 +490,26 @@ func parseConnectDSNAndArgs(
 		}
 	}
 
+	if tlsCAFile != "" {
+		tlsCAData, err = ioutil.ReadFile(tlsCAFile)
+		if err != nil {
+			return nil, &configurationError{msg: fmt.Sprintf(
+				"cannot read TLS certificate authority file %q: %v",
+				tlsCAFile, err,
+			)}
+		}
+	}
+
+	if opts.TLSConfig != nil {
+		tlsSecurity = ""
+		tlsCAData = nil
+	} else {
+		tlsSecurity, err = resolveTLSSecurity(tlsSecurity, tlsCAData)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	waitUntilAvailable := opts.WaitUntilAvailable
 	if waitUntilAvailable == 0 {
 		waitUntilAvailable = 30 * time.Second
@@ -452,10 +519,20 @@ func parseConnectDSNAndArgs(
 		addrs:              addrs,
 		user:               user,
 		password:           password,
+		passwordProvider:   opts.PasswordProvider,
 		database:           database,
 		connectTimeout:     opts.ConnectTimeout,
 		waitUntilAvailable: waitUntilAvailable,
 		serverSettings:     serverSettings,
+		onServerMessage:    opts.OnServerMessage,
+		dialer:             opts.Dialer,
+		keepAlive:          opts.KeepAlive,
+		readBufferSize:     opts.ReadBufferSize,
+		writeBufferSize:    opts.WriteBufferSize,
+		transcript:         opts.Transcript,
+		tlsConfig:          opts.TLSConfig,
+		tlsSecurity:        tlsSecurity,
+		tlsCAData:          tlsCAData,
 	}
 
 	return cfg, nil
@@ -392,6 +392,51 @@ func TestConUtils(t *testing.T) {
 				errMessage: "edgedb.ConfigurationError: could not match 2 port numbers to 3 hosts", // nolint:lll
 			},
 		},
+		{
+			name: "DSN with TLS security",
+			dsn:  "edgedb://user@host/db?tls_security=insecure",
+			expected: Result{
+				cfg: connConfig{
+					addrs:              []*dialArgs{{"tcp", "host:5656"}},
+					user:               "user",
+					database:           "db",
+					serverSettings:     map[string]string{},
+					waitUntilAvailable: 30 * time.Second,
+					tlsSecurity:        TLSModeInsecure,
+				},
+			},
+		},
+		{
+			name: "TLS security option is used before DSN",
+			dsn:  "edgedb://user@host/db?tls_security=insecure",
+			opts: Options{TLSSecurity: TLSModeDefault},
+			expected: Result{
+				cfg: connConfig{
+					addrs:              []*dialArgs{{"tcp", "host:5656"}},
+					user:               "user",
+					database:           "db",
+					serverSettings:     map[string]string{},
+					waitUntilAvailable: 30 * time.Second,
+					tlsSecurity:        TLSModeStrict,
+				},
+			},
+		},
+		{
+			name: "DSN with invalid TLS security",
+			dsn:  "edgedb://user@host/db?tls_security=none",
+			expected: Result{
+				err:        &configurationError{},
+				errMessage: `edgedb.ConfigurationError: invalid TLS security mode: "none", must be one of default, strict, no_host_verification or insecure`, // nolint:lll
+			},
+		},
+		{
+			name: "DSN with missing TLS certificate authority file",
+			dsn:  "edgedb://user@host/db?tls_ca_file=/non/existent",
+			expected: Result{
+				err:        &configurationError{},
+				errMessage: `edgedb.ConfigurationError: cannot read TLS certificate authority file "/non/existent": open /non/existent: no such file or directory`, // nolint:lll
+			},
+		},
 		{
 			name: "DSN query parameter with unix socket",
 			dsn:  "edgedb://user@?port=56226&host=%2Ftmp",
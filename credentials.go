@@ -30,6 +30,12 @@ type credentials struct {
 	user     string
 	database string
 	password string
+
+	// certData is the PEM encoded certificate authority data.
+	certData []byte
+
+	// verifyHostname is nil if the credentials do not specify it.
+	verifyHostname *bool
 }
 
 func readCredentials(path string) (*credentials, error) {
@@ -103,5 +109,21 @@ func validateCredentials(data map[string]interface{}) (*credentials, error) {
 		}
 	}
 
+	if certData, ok := data["tls_cert_data"]; ok {
+		str, ok := certData.(string)
+		if !ok {
+			return nil, errors.New("`tls_cert_data` must be a string")
+		}
+		result.certData = []byte(str)
+	}
+
+	if verify, ok := data["tls_verify_hostname"]; ok {
+		b, ok := verify.(bool)
+		if !ok {
+			return nil, errors.New("`tls_verify_hostname` must be a boolean")
+		}
+		result.verifyHostname = &b
+	}
+
 	return result, nil
 }
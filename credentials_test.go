@@ -72,3 +72,35 @@ func TestCredentialsPort(t *testing.T) {
 	assert.EqualError(t, err, "invalid `port` value")
 	assert.Nil(t, creds)
 }
+
+func TestCredentialsTLS(t *testing.T) {
+	creds, err := validateCredentials(map[string]interface{}{
+		"user":                "u1",
+		"tls_cert_data":       "cert data",
+		"tls_verify_hostname": false,
+	})
+	require.Nil(t, err)
+
+	verify := false
+	expected := &credentials{
+		port:           5656,
+		user:           "u1",
+		certData:       []byte("cert data"),
+		verifyHostname: &verify,
+	}
+	assert.Equal(t, expected, creds)
+
+	creds, err = validateCredentials(map[string]interface{}{
+		"user":          "u1",
+		"tls_cert_data": 1,
+	})
+	assert.EqualError(t, err, "`tls_cert_data` must be a string")
+	assert.Nil(t, creds)
+
+	creds, err = validateCredentials(map[string]interface{}{
+		"user":                "u1",
+		"tls_verify_hostname": "yes",
+	})
+	assert.EqualError(t, err, "`tls_verify_hostname` must be a boolean")
+	assert.Nil(t, creds)
+}
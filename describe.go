@@ -0,0 +1,247 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedb
+
+import (
+	"context"
+
+	"github.com/edgedb/edgedb-go/internal/buff"
+	"github.com/edgedb/edgedb-go/internal/cardinality"
+	"github.com/edgedb/edgedb-go/internal/descriptor"
+	"github.com/edgedb/edgedb-go/internal/format"
+)
+
+// Cardinality is the number of elements a query or field can have.
+type Cardinality string
+
+// Cardinalities
+const (
+	// CardinalityNoResult is the cardinality of commands
+	// that do not return data.
+	CardinalityNoResult Cardinality = "NO_RESULT"
+
+	// CardinalityOne means zero or one elements.
+	CardinalityOne Cardinality = "ONE"
+
+	// CardinalityMany means any number of elements.
+	CardinalityMany Cardinality = "MANY"
+
+	// CardinalityExactlyOne and CardinalityAtLeastOne
+	// are only reported by protocol 1.0 and later.
+	CardinalityExactlyOne Cardinality = "EXACTLY_ONE"
+	CardinalityAtLeastOne Cardinality = "AT_LEAST_ONE"
+)
+
+// TypeKind is the kind of a described type.
+type TypeKind string
+
+// Type kinds
+const (
+	KindScalar     TypeKind = "scalar"
+	KindEnum       TypeKind = "enum"
+	KindObject     TypeKind = "object"
+	KindSet        TypeKind = "set"
+	KindArray      TypeKind = "array"
+	KindTuple      TypeKind = "tuple"
+	KindNamedTuple TypeKind = "namedtuple"
+)
+
+// QueryDescription describes the input and output types of a query.
+type QueryDescription struct {
+	// Cardinality is the cardinality of the query's result.
+	Cardinality Cardinality
+
+	// Arguments are the query's arguments in the order they are declared.
+	// Positional arguments are named by their index.
+	Arguments []*FieldDescription
+
+	// Output is the type of the query's result elements.
+	// It is nil if the query does not return data.
+	Output *TypeDescription
+}
+
+// TypeDescription describes a type.
+type TypeDescription struct {
+	Kind TypeKind
+
+	// ID is the type descriptor ID sent by the server.
+	ID UUID
+
+	// Name is set for scalar types, for example std::str.
+	// Custom scalars are described by their base type.
+	Name string
+
+	// Element is the element type of sets and arrays.
+	Element *TypeDescription
+
+	// Fields are the fields of objects, tuples and named tuples.
+	Fields []*FieldDescription
+}
+
+// FieldDescription describes an object, tuple or named tuple field.
+type FieldDescription struct {
+	Name string
+	Type *TypeDescription
+
+	// The following are only set for object fields.
+	IsImplicit     bool
+	IsLinkProperty bool
+	IsLink         bool
+
+	// Cardinality is empty if the server did not report it,
+	// protocol 1.0 and later report it.
+	Cardinality Cardinality
+}
+
+var scalarTypeNames = map[UUID]string{
+	{14: 1, 15: 0x00}: "std::uuid",
+	{14: 1, 15: 0x01}: "std::str",
+	{14: 1, 15: 0x02}: "std::bytes",
+	{14: 1, 15: 0x03}: "std::int16",
+	{14: 1, 15: 0x04}: "std::int32",
+	{14: 1, 15: 0x05}: "std::int64",
+	{14: 1, 15: 0x06}: "std::float32",
+	{14: 1, 15: 0x07}: "std::float64",
+	{14: 1, 15: 0x08}: "std::decimal",
+	{14: 1, 15: 0x09}: "std::bool",
+	{14: 1, 15: 0x0a}: "std::datetime",
+	{14: 1, 15: 0x0b}: "cal::local_datetime",
+	{14: 1, 15: 0x0c}: "cal::local_date",
+	{14: 1, 15: 0x0d}: "cal::local_time",
+	{14: 1, 15: 0x0e}: "std::duration",
+	{14: 1, 15: 0x0f}: "std::json",
+	{14: 1, 15: 0x10}: "std::bigint",
+	{14: 1, 15: 0x11}: "cal::relative_duration",
+	{14: 1, 15: 0x12}: "cal::date_duration",
+	{14: 1, 15: 0x30}: "cfg::memory",
+}
+
+// Describe returns the input and output types of a query
+// without running it.
+func (c *baseConn) Describe(
+	ctx context.Context,
+	q *gfQuery,
+) (*QueryDescription, error) {
+	var descs descPair
+	finished, err := c.runFlow(ctx, func(r *buff.Reader) error {
+		var e error
+		descs, e = c.describeQuery(r, q)
+		return e
+	})
+
+	if !finished || err != nil {
+		return nil, err
+	}
+
+	return newQueryDescription(descs), nil
+}
+
+func (c *baseConn) describeQuery(
+	r *buff.Reader,
+	q *gfQuery,
+) (descPair, error) {
+	if c.protocolVersion.gte(protocolVersion1p0) {
+		_, descs, err := c.parse(r, q)
+		return descs, err
+	}
+
+	if len(q.state) > 0 {
+		return descPair{}, errStateNotSupported
+	}
+
+	if _, e := c.prepare(r, q); e != nil {
+		return descPair{}, e
+	}
+
+	return c.describe(r, q)
+}
+
+// newDescribeQuery returns a new granular flow query for Describe.
+func newDescribeQuery(cmd string, headers msgHeaders) *gfQuery {
+	return &gfQuery{
+		cmd:     cmd,
+		fmt:     format.Binary,
+		expCard: cardinality.Many,
+		headers: headers,
+	}
+}
+
+func newQueryDescription(descs descPair) *QueryDescription {
+	description := &QueryDescription{
+		Cardinality: Cardinality(cardinality.ToStr[descs.card]),
+	}
+
+	if descs.in.ID != descriptor.IDZero {
+		description.Arguments = describeFields(descs.in.Fields)
+	}
+
+	if descs.out.ID != descriptor.IDZero {
+		description.Output = describeType(descs.out)
+	}
+
+	return description
+}
+
+func describeType(desc descriptor.Descriptor) *TypeDescription {
+	typ := &TypeDescription{ID: desc.ID}
+
+	switch desc.Type {
+	case descriptor.BaseScalar:
+		typ.Kind = KindScalar
+		typ.Name = scalarTypeNames[desc.ID]
+	case descriptor.Enum:
+		typ.Kind = KindEnum
+	case descriptor.Object, descriptor.InputShape:
+		typ.Kind = KindObject
+		typ.Fields = describeFields(desc.Fields)
+	case descriptor.Set:
+		typ.Kind = KindSet
+		typ.Element = describeType(desc.Fields[0].Desc)
+	case descriptor.Array:
+		typ.Kind = KindArray
+		typ.Element = describeType(desc.Fields[0].Desc)
+	case descriptor.Tuple:
+		typ.Kind = KindTuple
+		typ.Fields = describeFields(desc.Fields)
+	case descriptor.NamedTuple:
+		typ.Kind = KindNamedTuple
+		typ.Fields = describeFields(desc.Fields)
+	}
+
+	return typ
+}
+
+func describeFields(fields []*descriptor.Field) []*FieldDescription {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	described := make([]*FieldDescription, len(fields))
+	for i, field := range fields {
+		card := cardinality.ToStr[field.Cardinality]
+		described[i] = &FieldDescription{
+			Name:           field.Name,
+			Type:           describeType(field.Desc),
+			IsImplicit:     field.Flags&descriptor.FlagImplicit != 0,
+			IsLinkProperty: field.Flags&descriptor.FlagLinkProperty != 0,
+			IsLink:         field.Flags&descriptor.FlagLink != 0,
+			Cardinality:    Cardinality(card),
+		}
+	}
+
+	return described
+}
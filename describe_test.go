@@ -0,0 +1,136 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/edgedb/edgedb-go/internal/cardinality"
+	"github.com/edgedb/edgedb-go/internal/descriptor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescribe(t *testing.T) {
+	ctx := context.Background()
+	p, err := Connect(ctx, opts)
+	require.Nil(t, err)
+	defer p.Close() // nolint:errcheck
+
+	description, err := p.Describe(
+		ctx,
+		"SELECT (name := <str>$name, values := [1, 2])",
+	)
+	require.Nil(t, err)
+
+	require.Equal(t, 1, len(description.Arguments))
+	assert.Equal(t, "name", description.Arguments[0].Name)
+	assert.Equal(t, "std::str", description.Arguments[0].Type.Name)
+
+	output := description.Output
+	require.NotNil(t, output)
+	assert.Equal(t, KindNamedTuple, output.Kind)
+	require.Equal(t, 2, len(output.Fields))
+	assert.Equal(t, "values", output.Fields[1].Name)
+	assert.Equal(t, KindArray, output.Fields[1].Type.Kind)
+}
+
+func TestDescribeCommand(t *testing.T) {
+	ctx := context.Background()
+	p, err := Connect(ctx, opts)
+	require.Nil(t, err)
+	defer p.Close() // nolint:errcheck
+
+	description, err := p.Describe(ctx, "CONFIGURE SESSION RESET ALL")
+	require.Nil(t, err)
+
+	assert.Equal(t, CardinalityNoResult, description.Cardinality)
+	assert.Nil(t, description.Arguments)
+	assert.Nil(t, description.Output)
+}
+
+func TestNewQueryDescription(t *testing.T) {
+	str := descriptor.Descriptor{
+		Type: descriptor.BaseScalar,
+		ID:   UUID{14: 1, 15: 1},
+	}
+
+	descs := descPair{
+		card: cardinality.Many,
+		in: descriptor.Descriptor{
+			Type:   descriptor.Tuple,
+			ID:     UUID{1},
+			Fields: []*descriptor.Field{{Name: "0", Desc: str}},
+		},
+		out: descriptor.Descriptor{
+			Type: descriptor.Object,
+			ID:   UUID{2},
+			Fields: []*descriptor.Field{
+				{
+					Name:  "id",
+					Desc:  str,
+					Flags: descriptor.FlagImplicit,
+				},
+				{
+					Name: "friends",
+					Desc: descriptor.Descriptor{
+						Type:   descriptor.Set,
+						ID:     UUID{3},
+						Fields: []*descriptor.Field{{Desc: str}},
+					},
+					Flags:       descriptor.FlagLink,
+					Cardinality: cardinality.Many,
+				},
+			},
+		},
+	}
+
+	scalar := &TypeDescription{
+		Kind: KindScalar,
+		ID:   UUID{14: 1, 15: 1},
+		Name: "std::str",
+	}
+
+	expected := &QueryDescription{
+		Cardinality: CardinalityMany,
+		Arguments:   []*FieldDescription{{Name: "0", Type: scalar}},
+		Output: &TypeDescription{
+			Kind: KindObject,
+			ID:   UUID{2},
+			Fields: []*FieldDescription{
+				{
+					Name:       "id",
+					Type:       scalar,
+					IsImplicit: true,
+				},
+				{
+					Name: "friends",
+					Type: &TypeDescription{
+						Kind:    KindSet,
+						ID:      UUID{3},
+						Element: scalar,
+					},
+					IsLink:      true,
+					Cardinality: CardinalityMany,
+				},
+			},
+		},
+	}
+
+	assert.Equal(t, expected, newQueryDescription(descs))
+}
@@ -0,0 +1,68 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedb
+
+import (
+	"context"
+	"net"
+)
+
+// DialFunc connects to address on the named network.
+// network is either "tcp" or "unix".
+type DialFunc func(
+	ctx context.Context,
+	network string,
+	address string,
+) (net.Conn, error)
+
+// dial opens a network connection to addr.
+func (c *connConfig) dial(
+	ctx context.Context,
+	addr *dialArgs,
+) (net.Conn, error) {
+	dial := c.dialer
+	if dial == nil {
+		d := net.Dialer{KeepAlive: c.keepAlive}
+		dial = d.DialContext
+	}
+
+	conn, err := dial(ctx, addr.network, addr.address)
+	if err != nil {
+		return nil, err
+	}
+
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return conn, nil
+	}
+
+	if c.readBufferSize > 0 {
+		if err := tcpConn.SetReadBuffer(c.readBufferSize); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+
+	if c.writeBufferSize > 0 {
+		if err := tcpConn.SetWriteBuffer(c.writeBufferSize); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
@@ -0,0 +1,102 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedb
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDialerPipe(t *testing.T) {
+	server := &stallingServer{unstall: make(chan struct{})}
+
+	var dialed []string
+	o := Options{
+		Hosts:    []string{"example.invalid"},
+		User:     "edgedb",
+		Database: "edgedb",
+		MinConns: 1,
+		MaxConns: 1,
+		Dialer: func(
+			ctx context.Context,
+			network string,
+			address string,
+		) (net.Conn, error) {
+			dialed = append(dialed, network+" "+address)
+			client, conn := net.Pipe()
+			go server.serve(conn)
+			return client, nil
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p, err := Connect(ctx, o)
+	require.Nil(t, err)
+	defer p.Close() // nolint:errcheck
+
+	require.Nil(t, p.Execute(ctx, "SELECT 1"))
+	assert.Equal(t, []string{"tcp example.invalid:5656"}, dialed)
+}
+
+func TestDialerError(t *testing.T) {
+	o := Options{
+		Hosts:              []string{"example.invalid"},
+		User:               "edgedb",
+		Database:           "edgedb",
+		WaitUntilAvailable: time.Nanosecond,
+		Dialer: func(
+			ctx context.Context,
+			network string,
+			address string,
+		) (net.Conn, error) {
+			return nil, &net.OpError{Op: "dial", Err: errors.New("boom")}
+		},
+	}
+
+	_, err := ConnectOne(context.Background(), o)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "boom")
+}
+
+func TestSocketOptions(t *testing.T) {
+	server := startStallingServer(t)
+	defer server.close()
+
+	o := server.options()
+	o.KeepAlive = 5 * time.Second
+	o.ReadBufferSize = 64 * 1024
+	o.WriteBufferSize = 64 * 1024
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p, err := Connect(ctx, o)
+	require.Nil(t, err)
+	defer p.Close() // nolint:errcheck
+
+	require.Nil(t, p.Execute(ctx, "SELECT 1"))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&server.accepted))
+}
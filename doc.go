@@ -73,6 +73,15 @@
 // returned from Connect() and ConnectDSN(). Pool.Acquire(), ConnectOne() and
 // ConnectOneDSN() will give you access to a single connection.
 //
+// Cancellation
+//
+// A query returns as soon as its context is done.
+// The server is then asked to cancel the running command
+// over a separate connection,
+// and the connection can be used again once the server has stopped it.
+// The connection is closed if the server does not stop the command
+// within 30 seconds.
+//
 // Errors
 //
 // edgedb never returns underlying errors directly.
@@ -121,8 +130,48 @@
 //   uuid                  edgedb.UUID
 //   json                  []byte
 //   bigint                *big.Int
+//   decimal               edgedb.Decimal
+//
+// Optional Values
+//
+// Shape fields that may be missing are decoded into pointers,
+// which are nil when the value is missing, or into the Optional types
+// like edgedb.OptionalStr and edgedb.OptionalInt64.
+// Other types are left unchanged when the value is missing.
+//
+//   var user struct {
+//       Name  string              `edgedb:"name"`
+//       Email *string             `edgedb:"email"`
+//       Age   edgedb.OptionalInt64 `edgedb:"age"`
+//   }
+//
+// Optional query arguments are passed as Optional types.
+// An Optional without a value is sent as an empty set.
 //
-//   decimal               user defined (see Custom Codecs)
+//   err := pool.Query(
+//       ctx,
+//       "SELECT User FILTER .age = <OPTIONAL int64>$0 ?? .age",
+//       &users,
+//       edgedb.OptionalInt64{},
+//   )
+//
+// Named Arguments
+//
+// Named query arguments are passed as a single map with string keys
+// or as a struct. Struct fields are matched to arguments
+// by their edgedb tag or by name. Every argument must have a value
+// and there must not be values for other names.
+//
+//   type Params struct {
+//       Name string `edgedb:"name"`
+//   }
+//
+//   err := pool.Query(
+//       ctx,
+//       "SELECT User { name } FILTER .name = <str>$name",
+//       &users,
+//       Params{Name: "Bob"},
+//   )
 //
 // Custom Codecs
 //
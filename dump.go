@@ -0,0 +1,351 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedb
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/edgedb/edgedb-go/internal/buff"
+	"github.com/edgedb/edgedb-go/internal/message"
+)
+
+// The dump file format is the same as the one produced by the edgedb CLI:
+//
+//   magic, int64 format version
+//   'H', sha1, uint32 length, DumpHeader data
+//   'D', sha1, uint32 length, DumpBlock data
+//   ...
+var dumpMagic = []byte("\xFF\xD8\x00\x00\xD8EDGEDB\x00DUMP\x00")
+
+const (
+	dumpFormatVersion int64 = 1
+	dumpHeaderBlock   byte  = 'H'
+	dumpDataBlock     byte  = 'D'
+)
+
+func writeDumpBlock(w io.Writer, typ byte, data []byte) error {
+	hdr := make([]byte, 1, 25)
+	hdr[0] = typ
+	sum := sha1.Sum(data)
+	hdr = append(hdr, sum[:]...)
+	hdr = append(hdr, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(hdr[21:], uint32(len(data)))
+
+	if _, err := w.Write(hdr); err != nil {
+		return err
+	}
+
+	_, err := w.Write(data)
+	return err
+}
+
+func readDumpPreamble(r io.Reader) error {
+	buf := make([]byte, len(dumpMagic)+8)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return fmt.Errorf("invalid dump file: %v", err)
+	}
+
+	if !bytes.Equal(buf[:len(dumpMagic)], dumpMagic) {
+		return errors.New("invalid dump file: incorrect file header")
+	}
+
+	ver := int64(binary.BigEndian.Uint64(buf[len(dumpMagic):]))
+	if ver != dumpFormatVersion {
+		return fmt.Errorf("unsupported dump file format version: %v", ver)
+	}
+
+	return nil
+}
+
+// readDumpBlock returns io.EOF if there are no more blocks.
+func readDumpBlock(r io.Reader) (byte, []byte, error) {
+	hdr := make([]byte, 25)
+	n, err := io.ReadFull(r, hdr)
+	switch {
+	case err == io.EOF:
+		return 0, nil, io.EOF
+	case err != nil:
+		return 0, nil, fmt.Errorf(
+			"invalid dump file: truncated block header (%v bytes)", n,
+		)
+	}
+
+	typ := hdr[0]
+	if typ != dumpHeaderBlock && typ != dumpDataBlock {
+		return 0, nil, fmt.Errorf(
+			"invalid dump file: unknown block type 0x%x", typ,
+		)
+	}
+
+	data := make([]byte, binary.BigEndian.Uint32(hdr[21:]))
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, errors.New("invalid dump file: truncated block")
+	}
+
+	if sum := sha1.Sum(data); !bytes.Equal(sum[:], hdr[1:21]) {
+		return 0, nil, errors.New("invalid dump file: block checksum mismatch")
+	}
+
+	return typ, data, nil
+}
+
+func (c *baseConn) dump(r *buff.Reader, out io.Writer) error {
+	w := buff.NewWriter(c.writeMemory[:0])
+	w.BeginMessage(message.Dump)
+	w.PushUint16(0) // no headers
+	w.EndMessage()
+
+	w.BeginMessage(message.Sync)
+	w.EndMessage()
+
+	if e := w.Send(c.conn); e != nil {
+		return &clientConnectionError{err: e}
+	}
+
+	var (
+		err      error
+		writeErr error
+	)
+
+	write := func(typ byte) {
+		if writeErr == nil {
+			writeErr = writeDumpBlock(out, typ, r.Buf)
+		}
+		r.DiscardMessage()
+	}
+
+	if _, e := out.Write(dumpMagic); e != nil {
+		writeErr = e
+	} else {
+		version := make([]byte, 8)
+		binary.BigEndian.PutUint64(version, uint64(dumpFormatVersion))
+		_, writeErr = out.Write(version)
+	}
+
+	done := buff.NewSignal()
+
+	for r.Next(done.Chan) {
+		switch r.MsgType {
+		case message.DumpHeader:
+			write(dumpHeaderBlock)
+		case message.DumpBlock:
+			write(dumpDataBlock)
+		case message.CommandComplete:
+			ignoreHeaders(r)
+			r.PopBytes() // command status
+		case message.ReadyForCommand:
+			ignoreHeaders(r)
+			r.Discard(1) // transaction state
+			done.Signal()
+		case message.ErrorResponse:
+			err = wrapAll(err, decodeError(r, ""))
+		default:
+			if e := c.fallThrough(r); e != nil {
+				// the connection will not be usable after this x_x
+				return e
+			}
+		}
+	}
+
+	if r.Err != nil {
+		return &clientConnectionError{err: r.Err}
+	}
+
+	if writeErr != nil {
+		err = wrapAll(err, &clientError{err: writeErr})
+	}
+
+	return err
+}
+
+func (c *baseConn) restore(r *buff.Reader, in io.Reader) error {
+	if e := readDumpPreamble(in); e != nil {
+		return &clientError{err: e}
+	}
+
+	typ, header, err := readDumpBlock(in)
+	switch {
+	case err == io.EOF:
+		return &clientError{msg: "invalid dump file: no header block"}
+	case err != nil:
+		return &clientError{err: err}
+	case typ != dumpHeaderBlock:
+		return &clientError{msg: "invalid dump file: no header block"}
+	}
+
+	w := buff.NewWriter(c.writeMemory[:0])
+	w.BeginMessage(message.Restore)
+	w.PushUint16(0) // no headers
+	w.PushUint16(1) // jobs
+	w.PushBytes(header)
+	w.EndMessage()
+
+	if e := w.Send(c.conn); e != nil {
+		return &clientConnectionError{err: e}
+	}
+
+	ready, err := c.waitRestoreReady(r)
+	if !ready {
+		return err
+	}
+
+	for {
+		typ, data, e := readDumpBlock(in)
+		if e == io.EOF {
+			break
+		}
+
+		if e == nil && typ != dumpDataBlock {
+			e = errors.New("invalid dump file: unexpected header block")
+		}
+
+		if e != nil {
+			// There is no way to abort a restore
+			// other than closing the connection.
+			_ = c.conn.Close()
+			c.errUnrecoverable = &clientConnectionClosedError{
+				msg: "connection closed after a failed restore",
+			}
+			return &clientError{err: e}
+		}
+
+		w = buff.NewWriter(c.writeMemory[:0])
+		w.BeginMessage(message.RestoreBlock)
+		w.PushBytes(data)
+		w.EndMessage()
+
+		if e := w.Send(c.conn); e != nil {
+			return &clientConnectionError{err: e}
+		}
+	}
+
+	w = buff.NewWriter(c.writeMemory[:0])
+	w.BeginMessage(message.RestoreEOF)
+	w.EndMessage()
+
+	if e := w.Send(c.conn); e != nil {
+		return &clientConnectionError{err: e}
+	}
+
+	return c.waitCommandComplete(r)
+}
+
+// waitRestoreReady reads messages until the server is ready
+// to receive restore blocks or the restore has failed.
+func (c *baseConn) waitRestoreReady(r *buff.Reader) (bool, error) {
+	var (
+		err   error
+		ready bool
+	)
+
+	done := buff.NewSignal()
+
+	for r.Next(done.Chan) {
+		switch r.MsgType {
+		case message.RestoreReady:
+			ignoreHeaders(r)
+			r.Discard(2) // jobs
+			ready = true
+			done.Signal()
+		case message.ReadyForCommand:
+			ignoreHeaders(r)
+			r.Discard(1) // transaction state
+			done.Signal()
+		case message.ErrorResponse:
+			err = wrapAll(err, decodeError(r, ""))
+		default:
+			if e := c.fallThrough(r); e != nil {
+				// the connection will not be usable after this x_x
+				return false, e
+			}
+		}
+	}
+
+	if r.Err != nil {
+		return false, &clientConnectionError{err: r.Err}
+	}
+
+	return ready && err == nil, err
+}
+
+func (c *baseConn) waitCommandComplete(r *buff.Reader) error {
+	var err error
+	done := buff.NewSignal()
+
+	for r.Next(done.Chan) {
+		switch r.MsgType {
+		case message.CommandComplete:
+			ignoreHeaders(r)
+			r.PopBytes() // command status
+		case message.ReadyForCommand:
+			ignoreHeaders(r)
+			r.Discard(1) // transaction state
+			done.Signal()
+		case message.ErrorResponse:
+			err = wrapAll(err, decodeError(r, ""))
+		default:
+			if e := c.fallThrough(r); e != nil {
+				// the connection will not be usable after this x_x
+				return e
+			}
+		}
+	}
+
+	if r.Err != nil {
+		return &clientConnectionError{err: r.Err}
+	}
+
+	return err
+}
+
+func (c *baseConn) Dump(ctx context.Context, out io.Writer) error {
+	r, err := c.acquireReader(ctx)
+	if err != nil {
+		return err
+	}
+
+	if e := c.setDeadline(ctx); e != nil {
+		return e
+	}
+
+	return c.releaseReader(r, c.dump(r, out))
+}
+
+func (c *baseConn) Restore(ctx context.Context, in io.Reader) error {
+	r, err := c.acquireReader(ctx)
+	if err != nil {
+		return err
+	}
+
+	if e := c.setDeadline(ctx); e != nil {
+		return e
+	}
+
+	err = c.restore(r, in)
+	if c.errUnrecoverable != nil {
+		// the restore was aborted by closing the connection.
+		return err
+	}
+
+	return c.releaseReader(r, err)
+}
@@ -0,0 +1,118 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedb
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpRestore(t *testing.T) {
+	ctx := context.Background()
+	err := conn.Execute(ctx, "CREATE DATABASE dump_restore;")
+	require.Nil(t, err)
+
+	var dump bytes.Buffer
+	err = conn.Dump(ctx, &dump)
+	require.Nil(t, err)
+	assert.True(t, bytes.HasPrefix(dump.Bytes(), dumpMagic))
+
+	o := opts
+	o.Database = "dump_restore"
+	restored, err := ConnectOne(ctx, o)
+	require.Nil(t, err)
+	defer restored.Close() // nolint:errcheck
+
+	err = restored.Restore(ctx, &dump)
+	require.Nil(t, err)
+
+	var count int64
+	query := "SELECT count(schema::ObjectType FILTER .name = 'default::User')"
+	err = restored.QueryOne(ctx, query, &count)
+	require.Nil(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestRestoreInvalidFile(t *testing.T) {
+	ctx := context.Background()
+	err := conn.Restore(ctx, bytes.NewBufferString("not a dump file"))
+	assert.EqualError(
+		t,
+		err,
+		"edgedb.ClientError: invalid dump file: unexpected EOF",
+	)
+
+	// the connection is still usable
+	var result int64
+	err = conn.QueryOne(ctx, "SELECT 1", &result)
+	require.Nil(t, err)
+	assert.Equal(t, int64(1), result)
+}
+
+func TestDumpFileFormat(t *testing.T) {
+	var buf bytes.Buffer
+	buf.Write(dumpMagic)
+	buf.Write([]byte{0, 0, 0, 0, 0, 0, 0, 1})
+	require.Nil(t, writeDumpBlock(&buf, dumpHeaderBlock, []byte("header")))
+	require.Nil(t, writeDumpBlock(&buf, dumpDataBlock, []byte("data")))
+
+	r := bytes.NewReader(buf.Bytes())
+	require.Nil(t, readDumpPreamble(r))
+
+	typ, data, err := readDumpBlock(r)
+	require.Nil(t, err)
+	assert.Equal(t, dumpHeaderBlock, typ)
+	assert.Equal(t, []byte("header"), data)
+
+	typ, data, err = readDumpBlock(r)
+	require.Nil(t, err)
+	assert.Equal(t, dumpDataBlock, typ)
+	assert.Equal(t, []byte("data"), data)
+
+	_, _, err = readDumpBlock(r)
+	assert.Equal(t, io.EOF, err)
+
+	corrupted := buf.Bytes()
+	corrupted[len(corrupted)-1] = 'x'
+	r = bytes.NewReader(corrupted)
+	require.Nil(t, readDumpPreamble(r))
+	_, _, err = readDumpBlock(r)
+	require.Nil(t, err)
+	_, _, err = readDumpBlock(r)
+	assert.EqualError(t, err, "invalid dump file: block checksum mismatch")
+}
+
+func TestDumpFileVersion(t *testing.T) {
+	var buf bytes.Buffer
+	buf.Write(dumpMagic)
+	buf.Write([]byte{0, 0, 0, 0, 0, 0, 0, 2})
+
+	err := readDumpPreamble(&buf)
+	assert.EqualError(t, err, "unsupported dump file format version: 2")
+
+	buf.Reset()
+	buf.Write([]byte("\xFF\xD8\x00\x00\xD8EDGEDB\x00DUMB\x00"))
+	buf.Write([]byte{0, 0, 0, 0, 0, 0, 0, 1})
+
+	err = readDumpPreamble(&buf)
+	assert.EqualError(t, err, "invalid dump file: incorrect file header")
+}
@@ -22,16 +22,25 @@ import (
 	"fmt"
 	"math/rand"
 	"net"
+	"reflect"
 	"syscall"
 	"time"
 
 	"github.com/edgedb/edgedb-go/internal/buff"
 	"github.com/edgedb/edgedb-go/internal/cache"
+	"github.com/edgedb/edgedb-go/internal/descriptor"
+	"github.com/edgedb/edgedb-go/internal/message"
 	"github.com/edgedb/edgedb-go/internal/soc"
 )
 
 var rnd = rand.New(rand.NewSource(time.Now().UnixNano()))
 
+// cancelDrainTimeout is how long the server is given to cancel a command
+// after the context it was started with is done.
+// If the server is not ready for the next command by then
+// the connection is closed.
+const cancelDrainTimeout = 30 * time.Second
+
 // Action is work to be done in a transaction.
 type Action func(context.Context, *Tx) error
 
@@ -49,13 +58,27 @@ type baseConn struct {
 	inCodecCache  *cache.Cache
 	outCodecCache *cache.Cache
 
+	// serverSettings holds the parameters reported by the server.
+	serverSettings *settingsStore
+
 	protocolVersion version
 
+	// stateDesc describes the session state.
+	// It is only sent by protocol 1.0 and later.
+	stateDesc descriptor.Descriptor
+
 	// indicates whether the protocol version supports
 	// the EXPLICIT_OBJECTIDS header.
 	explicitIDs bool
 
 	cfg *connConfig
+
+	// addr is the address the connection is connected to.
+	addr *dialArgs
+
+	// keyData identifies the connection to the server
+	// when cancelling its running command.
+	keyData [32]byte
 }
 
 // connectWithTimeout makes a single attempt to connect to `addr`.
@@ -66,7 +89,6 @@ func connectWithTimeout(
 ) error {
 	var (
 		cancel context.CancelFunc
-		d      net.Dialer
 		err    error
 	)
 
@@ -78,14 +100,11 @@ func connectWithTimeout(
 	toBeDeserialized := make(chan *soc.Data, 2)
 	r := buff.NewReader(toBeDeserialized)
 
-	conn.conn, err = d.DialContext(ctx, addr.network, addr.address)
+	conn.conn, err = conn.cfg.dial(ctx, addr)
 	if err != nil {
 		goto handleError
 	}
-
-	conn.acquireReaderSignal = make(chan struct{}, 1)
-	conn.readerChan = make(chan *buff.Reader, 1)
-	go soc.Read(conn.conn, soc.NewMemPool(4, 256*1024), toBeDeserialized)
+	conn.addr = addr
 
 	err = conn.setDeadline(ctx)
 	if err != nil {
@@ -93,7 +112,21 @@ func connectWithTimeout(
 		goto handleError
 	}
 
-	err = conn.connect(r, conn.cfg)
+	err = conn.upgradeTLS(addr)
+	if err != nil {
+		_ = conn.conn.Close()
+		goto handleError
+	}
+
+	if conn.cfg.transcript != nil {
+		conn.conn = recordTranscript(conn.cfg.transcript, conn.conn, addr)
+	}
+
+	conn.acquireReaderSignal = make(chan struct{}, 1)
+	conn.readerChan = make(chan *buff.Reader, 1)
+	go soc.Read(conn.conn, soc.NewMemPool(4, 256*1024), toBeDeserialized)
+
+	err = conn.connect(ctx, r, conn.cfg)
 	if err != nil {
 		_ = conn.conn.Close()
 		goto handleError
@@ -161,7 +194,18 @@ func (c *baseConn) acquireReader(ctx context.Context) (*buff.Reader, error) {
 	select {
 	case r := <-c.readerChan:
 		if r.Err != nil {
-			return nil, &clientConnectionError{err: r.Err}
+			// The reader is not returned to readerChan
+			// so the connection can not be used again.
+			// Background goroutines report errors through r.Err
+			// because errUnrecoverable is only set by the reader's owner.
+			var edbErr Error
+			if errors.As(r.Err, &edbErr) {
+				c.errUnrecoverable = r.Err
+			} else {
+				c.errUnrecoverable = &clientConnectionError{err: r.Err}
+			}
+
+			return nil, c.errUnrecoverable
 		}
 
 		return r, nil
@@ -186,9 +230,9 @@ func (c *baseConn) releaseReader(r *buff.Reader, err error) error {
 	go func() {
 		for r.Next(c.acquireReaderSignal) {
 			if e := c.fallThrough(r); e != nil {
-				c.errUnrecoverable = e
 				_ = c.conn.Close()
-				return
+				r.Err = e
+				break
 			}
 		}
 
@@ -200,6 +244,11 @@ func (c *baseConn) releaseReader(r *buff.Reader, err error) error {
 
 // Close the db connection
 func (c *baseConn) close() error {
+	if c.conn == nil {
+		// the socket was already closed after a network error.
+		return nil
+	}
+
 	_, err := c.acquireReader(context.Background())
 	if err != nil {
 		_ = c.conn.Close()
@@ -224,27 +273,136 @@ func (c *baseConn) close() error {
 }
 
 func (c *baseConn) ScriptFlow(ctx context.Context, q sfQuery) error {
+	_, err := c.runFlow(ctx, func(r *buff.Reader) error {
+		return c.scriptFlow(r, q)
+	})
+
+	return err
+}
+
+func (c *baseConn) GranularFlow(ctx context.Context, q *gfQuery) error {
+	// Results are decoded into a private value
+	// so that a cancelled query that is still being drained
+	// does not write to the caller's memory.
+	out := q.out
+	q.out = reflect.New(out.Type()).Elem()
+
+	finished, err := c.runFlow(ctx, func(r *buff.Reader) error {
+		return c.granularFlow(r, q)
+	})
+
+	if finished && err == nil {
+		out.Set(q.out)
+	}
+
+	return err
+}
+
+// runFlow runs flow with the connection's reader.
+// If ctx is done before flow finishes, the context's error is returned
+// without waiting. The running command is then cancelled
+// and the connection is drained in the background.
+// finished is false if flow was still running when runFlow returned.
+func (c *baseConn) runFlow(
+	ctx context.Context,
+	flow func(*buff.Reader) error,
+) (finished bool, err error) {
 	r, err := c.acquireReader(ctx)
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	if e := c.setDeadline(ctx); e != nil {
-		return e
+	// The socket deadline is only a last resort,
+	// normally the context is done before it is reached.
+	deadline, ok := ctx.Deadline()
+	if ok {
+		deadline = deadline.Add(cancelDrainTimeout)
+	}
+
+	if e := c.conn.SetDeadline(deadline); e != nil {
+		return false, &clientConnectionError{err: e}
 	}
 
-	return c.releaseReader(r, c.scriptFlow(r, q))
+	result := make(chan error, 1)
+	go func() { result <- flow(r) }()
+
+	select {
+	case err = <-result:
+		return true, c.releaseReader(r, err)
+	case <-ctx.Done():
+		go c.drain(r, result)
+		return false, fmt.Errorf("edgedb: %w", ctx.Err())
+	}
 }
 
-func (c *baseConn) GranularFlow(ctx context.Context, q *gfQuery) error {
-	r, err := c.acquireReader(ctx)
+// drain cancels a flow that was abandoned because its context was done
+// and waits for it to finish.
+// If the server finishes the command the reader is released
+// and the connection can be used again,
+// otherwise the connection is closed.
+func (c *baseConn) drain(r *buff.Reader, result <-chan error) {
+	deadline := time.Now().Add(cancelDrainTimeout)
+	_ = c.conn.SetDeadline(deadline)
+
+	// The command finishes by itself if the cancel request fails.
+	_ = c.sendCancel(deadline)
+	err := <-result
+
+	var edbErr Error
+	broken := r.Err != nil ||
+		soc.IsPermanentNetErr(err) ||
+		errors.As(err, &edbErr) &&
+			(edbErr.Category(ClientConnectionError) ||
+				edbErr.Category(UnexpectedMessageError))
+
+	if !broken {
+		_ = c.releaseReader(r, err)
+		return
+	}
+
+	_ = c.conn.Close()
+	r.Err = &clientConnectionClosedError{
+		msg: "the connection was closed while draining a cancelled query",
+	}
+	c.readerChan <- r
+}
+
+// sendCancel asks the server to cancel the command running on c.
+// The request is sent on a separate connection
+// because c is busy until the command is finished.
+func (c *baseConn) sendCancel(deadline time.Time) error {
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	side := &baseConn{cfg: c.cfg}
+
+	var err error
+	side.conn, err = c.cfg.dial(ctx, c.addr)
 	if err != nil {
-		return err
+		return &clientConnectionFailedError{err: err}
 	}
+	defer func() { _ = side.conn.Close() }()
 
-	if e := c.setDeadline(ctx); e != nil {
+	if e := side.setDeadline(ctx); e != nil {
 		return e
 	}
 
-	return c.releaseReader(r, c.granularFlow(r, q))
+	if e := side.upgradeTLS(c.addr); e != nil {
+		return &clientConnectionFailedError{err: e}
+	}
+
+	if c.cfg.transcript != nil {
+		side.conn = recordTranscript(c.cfg.transcript, side.conn, c.addr)
+	}
+
+	w := buff.NewWriter(side.writeMemory[:0])
+	w.BeginMessage(message.CancelRequest)
+	w.PushBytes(c.keyData[:])
+	w.EndMessage()
+
+	if e := w.Send(side.conn); e != nil {
+		return &clientConnectionError{err: e}
+	}
+
+	return nil
 }
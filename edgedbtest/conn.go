@@ -0,0 +1,650 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedbtest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+
+	"github.com/edgedb/edgedb-go/internal/buff"
+	"github.com/edgedb/edgedb-go/internal/descriptor"
+	types "github.com/edgedb/edgedb-go/internal/edgedbtypes"
+	"github.com/edgedb/edgedb-go/internal/format"
+	"github.com/edgedb/edgedb-go/internal/message"
+)
+
+// The server speaks protocol 0.13
+// which uses Prepare, DescribeStatement and Execute messages.
+const (
+	protocolMajor = 0
+	protocolMinor = 13
+)
+
+// Transaction states sent in ReadyForCommand messages.
+const (
+	txIdle   = 'I'
+	txActive = 'T'
+	txFailed = 'E'
+)
+
+// healthCheck is the script that clients use to check connections.
+const healthCheck = "SELECT 1;"
+
+// txStatements are the transaction control statements
+// that are answered without a registered response.
+// Longer statements come before their prefixes.
+var txStatements = []string{
+	"START TRANSACTION",
+	"COMMIT",
+	"ROLLBACK TO SAVEPOINT",
+	"ROLLBACK",
+	"DECLARE SAVEPOINT",
+	"RELEASE SAVEPOINT",
+}
+
+var errTerminated = errors.New("terminated")
+
+// emptyTuple is the argument type of queries without arguments.
+var emptyTuple Type = &tuple{uuid: descriptor.IDEmptyTuple}
+
+// prepared is a statement prepared by a Prepare message.
+type prepared struct {
+	query   string
+	handler *handler
+	fmt     uint8
+	expCard uint8
+	ids     [2]types.UUID
+}
+
+// serverConn is the server side of a client connection.
+type serverConn struct {
+	server *Server
+	conn   net.Conn
+	reader *bufio.Reader
+
+	// pending holds messages until the client sends Sync.
+	pending bytes.Buffer
+
+	txState uint8
+
+	// failed is set after an error until the client sends Sync.
+	// Messages other than Sync are ignored while failed.
+	failed bool
+
+	stmt *prepared
+}
+
+func newServerConn(s *Server, conn net.Conn) *serverConn {
+	return &serverConn{
+		server:  s,
+		conn:    conn,
+		reader:  bufio.NewReader(conn),
+		txState: txIdle,
+	}
+}
+
+func (c *serverConn) serve() {
+	defer c.conn.Close() // nolint:errcheck
+
+	if err := c.handshake(); err != nil {
+		return
+	}
+
+	for {
+		mType, r, err := c.readMessage()
+		if err != nil {
+			return
+		}
+
+		if err := c.handle(mType, r); err != nil {
+			return
+		}
+	}
+}
+
+// readMessage reads the next message from the client.
+func (c *serverConn) readMessage() (uint8, *buff.Reader, error) {
+	head := make([]byte, 5)
+	if _, err := io.ReadFull(c.reader, head); err != nil {
+		return 0, nil, err
+	}
+
+	payload := make([]byte, binary.BigEndian.Uint32(head[1:])-4)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return 0, nil, err
+	}
+
+	return head[0], buff.SimpleReader(payload), nil
+}
+
+// expect reads the next message and returns an error
+// if it is not of type mType.
+func (c *serverConn) expect(mType uint8) (*buff.Reader, error) {
+	typ, r, err := c.readMessage()
+	if err != nil {
+		return nil, err
+	}
+
+	if typ != mType {
+		return nil, fmt.Errorf(
+			"expected message type 0x%x, got 0x%x", mType, typ,
+		)
+	}
+
+	return r, nil
+}
+
+// write adds the messages in w to the pending messages.
+func (c *serverConn) write(w *buff.Writer) {
+	_ = w.Send(&c.pending)
+}
+
+// flush sends the pending messages.
+func (c *serverConn) flush() error {
+	_, err := c.pending.WriteTo(c.conn)
+	return err
+}
+
+func (c *serverConn) handshake() error {
+	r, err := c.expect(message.ClientHandshake)
+	if err != nil {
+		return err
+	}
+
+	r.Discard(4) // protocol version
+	n := int(r.PopUint16())
+	for i := 0; i < n; i++ {
+		r.PopBytes() // parameter name
+		r.PopBytes() // parameter value
+	}
+
+	w := buff.NewWriter(nil)
+	w.BeginMessage(message.ServerHandshake)
+	w.PushUint16(protocolMajor)
+	w.PushUint16(protocolMinor)
+	w.PushUint16(0) // no extensions
+	w.EndMessage()
+	c.write(w)
+
+	if err := c.authenticate(); err != nil {
+		return err
+	}
+
+	w = buff.NewWriter(nil)
+	w.BeginMessage(message.ServerKeyData)
+	w.PushBytes(make([]byte, 32))
+	w.EndMessage()
+	c.write(w)
+
+	c.readyForCommand()
+	return c.flush()
+}
+
+func (c *serverConn) authenticate() error {
+	auth, err := c.server.authenticator()
+	if err != nil {
+		return err
+	}
+
+	w := buff.NewWriter(nil)
+	if auth == nil {
+		w.BeginMessage(message.Authentication)
+		w.PushUint32(0) // auth status
+		w.EndMessage()
+		c.write(w)
+		return nil
+	}
+
+	w.BeginMessage(message.Authentication)
+	w.PushUint32(0xa) // SASL required
+	w.PushUint32(1)   // number of methods
+	w.PushString("SCRAM-SHA-256")
+	w.EndMessage()
+	c.write(w)
+
+	if e := c.flush(); e != nil {
+		return e
+	}
+
+	r, err := c.expect(message.AuthenticationSASLInitialResponse)
+	if err != nil {
+		return err
+	}
+
+	r.PopBytes() // method
+	conv := auth.NewConversation()
+	serverFirst, err := conv.Step(r.PopString())
+	if err != nil {
+		return c.authenticationFailed(err)
+	}
+
+	w = buff.NewWriter(nil)
+	w.BeginMessage(message.Authentication)
+	w.PushUint32(0xb) // SASL continue
+	w.PushString(serverFirst)
+	w.EndMessage()
+	c.write(w)
+
+	if e := c.flush(); e != nil {
+		return e
+	}
+
+	r, err = c.expect(message.AuthenticationSASLResponse)
+	if err != nil {
+		return err
+	}
+
+	serverFinal, err := conv.Step(r.PopString())
+	if err != nil {
+		return c.authenticationFailed(err)
+	}
+
+	w = buff.NewWriter(nil)
+	w.BeginMessage(message.Authentication)
+	w.PushUint32(0xc) // SASL final
+	w.PushString(serverFinal)
+	w.EndMessage()
+
+	w.BeginMessage(message.Authentication)
+	w.PushUint32(0) // auth status
+	w.EndMessage()
+	c.write(w)
+
+	return nil
+}
+
+// authenticationFailed sends an authentication error
+// and returns err once the client closes the connection.
+func (c *serverConn) authenticationFailed(err error) error {
+	c.writeError(&Error{
+		Code:    AuthenticationError,
+		Message: "authentication failed",
+	})
+
+	if e := c.flush(); e != nil {
+		return e
+	}
+
+	// the connection is not closed first
+	// so that the client reads the error instead of EOF.
+	_, _ = io.Copy(ioutil.Discard, c.reader)
+	return err
+}
+
+func (c *serverConn) handle(mType uint8, r *buff.Reader) error {
+	if mType == message.Terminate {
+		return errTerminated
+	}
+
+	if mType == message.Sync {
+		c.failed = false
+		c.readyForCommand()
+		return c.flush()
+	}
+
+	if mType == message.Flush {
+		return c.flush()
+	}
+
+	if c.failed {
+		return nil
+	}
+
+	switch mType {
+	case message.Prepare:
+		c.prepare(r)
+	case message.DescribeStatement:
+		c.describe()
+	case message.Execute0pX:
+		c.execute()
+	case message.OptimisticExecute:
+		c.optimisticExecute(r)
+	case message.ExecuteScript:
+		// scripts are not followed by Sync.
+		c.executeScript(r)
+		c.failed = false
+		c.readyForCommand()
+		return c.flush()
+	default:
+		c.writeError(&Error{
+			Code: UnsupportedFeatureError,
+			Message: fmt.Sprintf(
+				"edgedbtest: unsupported message type 0x%x", mType,
+			),
+		})
+		c.failed = false
+		c.readyForCommand()
+		return c.flush()
+	}
+
+	return nil
+}
+
+func (c *serverConn) readyForCommand() {
+	w := buff.NewWriter(nil)
+	w.BeginMessage(message.ReadyForCommand)
+	w.PushUint16(0) // no headers
+	w.PushUint8(c.txState)
+	w.EndMessage()
+	c.write(w)
+}
+
+func (c *serverConn) writeError(e *Error) {
+	if c.txState == txActive {
+		c.txState = txFailed
+	}
+
+	c.failed = true
+
+	w := buff.NewWriter(nil)
+	w.BeginMessage(message.ErrorResponse)
+	w.PushUint8(0x78) // severity ERROR
+	w.PushUint32(e.Code)
+	w.PushString(e.Message)
+	w.PushUint16(0) // no attributes
+	w.EndMessage()
+	c.write(w)
+}
+
+// lookup returns the handler for query
+// or sends an error if there is none.
+func (c *serverConn) lookup(query string) *handler {
+	h := c.server.handler(query)
+	if h == nil {
+		c.writeError(&Error{
+			Code:    QueryError,
+			Message: fmt.Sprintf("edgedbtest: unexpected query: %q", query),
+		})
+	}
+
+	return h
+}
+
+func ignoreHeaders(r *buff.Reader) {
+	n := int(r.PopUint16())
+	for i := 0; i < n; i++ {
+		r.Discard(2) // header key
+		r.PopBytes() // header value
+	}
+}
+
+// types returns the argument and result types of res.
+func (p *prepared) types(res *Response) (Type, Type) {
+	args := res.Args
+	if args == nil {
+		args = emptyTuple
+	}
+
+	out := res.Type
+	if p.fmt == format.JSON {
+		out = Str
+	}
+
+	return args, out
+}
+
+// cardinality returns the result cardinality of res.
+func (p *prepared) cardinality(res *Response) uint8 {
+	_, out := p.types(res)
+
+	switch {
+	case res.Cardinality != 0:
+		return uint8(res.Cardinality)
+	case out == nil:
+		return uint8(NoResult)
+	default:
+		return p.expCard
+	}
+}
+
+func (c *serverConn) prepare(r *buff.Reader) {
+	ignoreHeaders(r)
+	stmt := &prepared{fmt: r.PopUint8(), expCard: r.PopUint8()}
+	r.PopBytes() // statement name
+	stmt.query = r.PopString()
+
+	stmt.handler = c.lookup(stmt.query)
+	if stmt.handler == nil {
+		return
+	}
+
+	res := stmt.handler.describe()
+	args, out := stmt.types(&res)
+	stmt.ids = [2]types.UUID{args.id(), descriptorID(out)}
+	c.stmt = stmt
+
+	w := buff.NewWriter(nil)
+	w.BeginMessage(message.PrepareComplete)
+	w.PushUint16(0) // no headers
+	w.PushUint8(stmt.cardinality(&res))
+	w.PushUUID(stmt.ids[0])
+	w.PushUUID(stmt.ids[1])
+	w.EndMessage()
+	c.write(w)
+}
+
+func (c *serverConn) describe() {
+	if c.stmt == nil {
+		c.writeError(&Error{
+			Code:    QueryError,
+			Message: "edgedbtest: no prepared statement",
+		})
+		return
+	}
+
+	res := c.stmt.handler.describe()
+	c.writeDescription(c.stmt, &res)
+}
+
+// writeDescription sends the type descriptors of res.
+func (c *serverConn) writeDescription(stmt *prepared, res *Response) {
+	args, out := stmt.types(res)
+	stmt.ids = [2]types.UUID{args.id(), descriptorID(out)}
+
+	w := buff.NewWriter(nil)
+	w.BeginMessage(message.CommandDataDescription)
+	w.PushUint16(0) // no headers
+	w.PushUint8(stmt.cardinality(res))
+	w.PushUUID(stmt.ids[0])
+	writeDescriptor(w, args)
+	w.PushUUID(stmt.ids[1])
+	writeDescriptor(w, out)
+	w.EndMessage()
+	c.write(w)
+}
+
+func (c *serverConn) execute() {
+	if c.stmt == nil {
+		c.writeError(&Error{
+			Code:    QueryError,
+			Message: "edgedbtest: no prepared statement",
+		})
+		return
+	}
+
+	c.server.executed(c.stmt.query)
+	c.writeResult(c.stmt, c.stmt.handler.respond())
+}
+
+func (c *serverConn) optimisticExecute(r *buff.Reader) {
+	ignoreHeaders(r)
+	stmt := &prepared{fmt: r.PopUint8(), expCard: r.PopUint8()}
+	stmt.query = r.PopString()
+	stmt.ids = [2]types.UUID{r.PopUUID(), r.PopUUID()}
+
+	stmt.handler = c.lookup(stmt.query)
+	if stmt.handler == nil {
+		return
+	}
+
+	// Like protocol 0.x servers, queries with stale type IDs
+	// are described instead of executed.
+	// The described query can be run with Execute.
+	res := stmt.handler.describe()
+	args, out := stmt.types(&res)
+	if stmt.ids != [2]types.UUID{args.id(), descriptorID(out)} {
+		c.stmt = stmt
+		c.writeDescription(stmt, &res)
+		return
+	}
+
+	c.server.executed(stmt.query)
+	c.writeResult(stmt, stmt.handler.respond())
+}
+
+// writeResult sends the results of an execution.
+// If the response's types differ from the ones the client knows
+// they are described first.
+func (c *serverConn) writeResult(stmt *prepared, res Response) {
+	if res.Error != nil {
+		c.writeError(res.Error)
+		return
+	}
+
+	args, out := stmt.types(&res)
+	if stmt.ids != [2]types.UUID{args.id(), descriptorID(out)} {
+		c.writeDescription(stmt, &res)
+	}
+
+	w := buff.NewWriter(nil)
+	if err := stmt.writeData(w, &res, out); err != nil {
+		var e *Error
+		if !errors.As(err, &e) {
+			e = &Error{Code: InternalServerError, Message: err.Error()}
+		}
+
+		c.writeError(e)
+		return
+	}
+
+	status := res.Status
+	if status == "" {
+		status = "OK"
+	}
+
+	w.BeginMessage(message.CommandComplete)
+	w.PushUint16(0) // no headers
+	w.PushString(status)
+	w.EndMessage()
+	c.write(w)
+}
+
+// writeData writes a Data message for each result.
+func (p *prepared) writeData(w *buff.Writer, res *Response, out Type) error {
+	if p.fmt == format.JSON {
+		result := res.JSON
+		if result == "" && p.expCard != uint8(One) {
+			result = "[]"
+		}
+
+		if result != "" {
+			w.BeginMessage(message.Data)
+			w.PushUint16(1) // number of elements
+			w.PushString(result)
+			w.EndMessage()
+		}
+
+		return nil
+	}
+
+	if len(res.Rows) > 0 && out == nil {
+		return errors.New("edgedbtest: rows of a response without a Type")
+	}
+
+	if len(res.Rows) > 1 && p.expCard == uint8(One) {
+		return &Error{
+			Code:    CardinalityViolationError,
+			Message: "more than one element returned by an expression",
+		}
+	}
+
+	for _, row := range res.Rows {
+		w.BeginMessage(message.Data)
+		w.PushUint16(1) // number of elements
+		if err := out.encode(w, row); err != nil {
+			return err
+		}
+		w.EndMessage()
+	}
+
+	return nil
+}
+
+func (c *serverConn) executeScript(r *buff.Reader) {
+	ignoreHeaders(r)
+	script := r.PopString()
+	c.server.executed(script)
+
+	status, ok := c.txStatement(script)
+	if !ok {
+		h := c.server.handler(script)
+		switch {
+		case h != nil:
+			res := h.respond()
+			if res.Error != nil {
+				c.writeError(res.Error)
+				return
+			}
+
+			status = res.Status
+			if status == "" {
+				status = "OK"
+			}
+		case script == healthCheck:
+			status = "SELECT"
+		default:
+			c.lookup(script)
+			return
+		}
+	}
+
+	w := buff.NewWriter(nil)
+	w.BeginMessage(message.CommandComplete)
+	w.PushUint16(0) // no headers
+	w.PushString(status)
+	w.EndMessage()
+	c.write(w)
+}
+
+// txStatement updates the transaction state
+// if script is a transaction control statement
+// and returns the statement's command status.
+func (c *serverConn) txStatement(script string) (string, bool) {
+	for _, stmt := range txStatements {
+		if !strings.HasPrefix(script, stmt) {
+			continue
+		}
+
+		switch stmt {
+		case "START TRANSACTION", "ROLLBACK TO SAVEPOINT":
+			c.txState = txActive
+		case "COMMIT", "ROLLBACK":
+			c.txState = txIdle
+		}
+
+		return stmt, true
+	}
+
+	return "", false
+}
@@ -0,0 +1,75 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedbtest_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/edgedb/edgedb-go"
+	"github.com/edgedb/edgedb-go/edgedbtest"
+)
+
+func Example() {
+	server, err := edgedbtest.NewServer()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer server.Close()
+
+	server.Handle(
+		"SELECT User { name }",
+		edgedbtest.Response{
+			Type: edgedbtest.Object(
+				edgedbtest.Field{Name: "name", Type: edgedbtest.Str},
+			),
+			Rows: []interface{}{
+				[]interface{}{"Alice"},
+				[]interface{}{"Bob"},
+			},
+		},
+	)
+
+	ctx := context.Background()
+	db, err := edgedb.Connect(ctx, edgedb.Options{
+		Hosts: []string{server.Host()},
+		Ports: []int{server.Port()},
+		User:  "edgedb",
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	var users []struct {
+		Name string `edgedb:"name"`
+	}
+
+	err = db.Query(ctx, "SELECT User { name }", &users)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, user := range users {
+		fmt.Println(user.Name)
+	}
+
+	// Output:
+	// Alice
+	// Bob
+}
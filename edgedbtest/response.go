@@ -0,0 +1,135 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedbtest
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/edgedb/edgedb-go/internal/cardinality"
+)
+
+// Cardinality is the number of results a query returns.
+type Cardinality uint8
+
+// The cardinalities sent to clients are:
+const (
+	NoResult Cardinality = cardinality.NoResult
+	One      Cardinality = cardinality.One
+	Many     Cardinality = cardinality.Many
+)
+
+// Error codes of some of the errors a server can respond with.
+// The client returns the error type for the code,
+// for example TransactionConflictError is retried by RetryingTx.
+// https://www.edgedb.com/docs/internals/protocol/errors
+const (
+	InternalServerError           uint32 = 0x01_00_00_00
+	UnsupportedFeatureError       uint32 = 0x02_00_00_00
+	DisabledCapabilityError       uint32 = 0x03_04_02_00
+	QueryError                    uint32 = 0x04_00_00_00
+	InvalidReferenceError         uint32 = 0x04_03_00_00
+	InvalidValueError             uint32 = 0x05_01_00_00
+	ConstraintViolationError      uint32 = 0x05_02_00_01
+	CardinalityViolationError     uint32 = 0x05_02_00_02
+	TransactionConflictError      uint32 = 0x05_03_01_00
+	TransactionSerializationError uint32 = 0x05_03_01_01
+	TransactionDeadlockError      uint32 = 0x05_03_01_02
+	AuthenticationError           uint32 = 0x07_01_00_00
+)
+
+// Error is an error response.
+type Error struct {
+	Code    uint32
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("edgedbtest: error 0x%x: %v", e.Code, e.Message)
+}
+
+// Response is a canned response to a query.
+type Response struct {
+	// Type is the type of the query's results.
+	// It is nil for queries that do not return data.
+	Type Type
+
+	// Args is the type of the query's arguments,
+	// a Tuple for positional arguments or a NamedTuple for named ones.
+	// It is nil for queries without arguments.
+	// The arguments clients send are not checked.
+	Args Type
+
+	// Cardinality is the query's result cardinality.
+	// If it is zero, NoResult is used for queries without a Type,
+	// otherwise the cardinality the client expects is used.
+	Cardinality Cardinality
+
+	// Rows are the results, encoded according to Type.
+	Rows []interface{}
+
+	// JSON is the result of queries that request JSON output.
+	// If it is empty, an empty array is sent
+	// to queries that expect many results
+	// and no results are sent to queries that expect one.
+	JSON string
+
+	// Status is the command status, for example "SELECT".
+	// It defaults to "OK".
+	Status string
+
+	// Error is sent instead of results if it is not nil.
+	Error *Error
+}
+
+// handler holds the responses to a query.
+// Each execution consumes a response,
+// the last response is repeated once the others are consumed.
+type handler struct {
+	mu        sync.Mutex
+	responses []Response
+	next      int
+}
+
+// describe returns the response that the query is described with,
+// which is the next response that has a Type
+// so that responses with errors do not change the query's type.
+func (h *handler) describe() Response {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i := h.next; i < len(h.responses); i++ {
+		if h.responses[i].Type != nil {
+			return h.responses[i]
+		}
+	}
+
+	return h.responses[h.next]
+}
+
+// respond returns the next response.
+func (h *handler) respond() Response {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	res := h.responses[h.next]
+	if h.next < len(h.responses)-1 {
+		h.next++
+	}
+
+	return res
+}
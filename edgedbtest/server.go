@@ -0,0 +1,223 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package edgedbtest implements a fake EdgeDB server for tests.
+//
+// The server speaks the server side of the binary protocol
+// so that code using an edgedb.Pool can be tested without a database.
+// Responses are registered for each query text
+// and are sent to clients in the order they were registered.
+// Clients connect to the server's Host and Port without TLS.
+//
+// Transaction statements and the "SELECT 1;" health check
+// are answered without registering them.
+package edgedbtest
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/xdg/scram"
+)
+
+// Server is a fake EdgeDB server listening on a local TCP port.
+type Server struct {
+	ln net.Listener
+	wg sync.WaitGroup
+
+	// salt is used for the SCRAM credentials of all users.
+	salt []byte
+
+	mu        sync.Mutex
+	conns     map[net.Conn]struct{}
+	handlers  map[string]*handler
+	passwords map[string]string
+	queries   []string
+	closed    bool
+}
+
+// NewServer starts a server on a random port of the loopback interface.
+func NewServer() (*Server, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		ln:       ln,
+		salt:     salt,
+		conns:    make(map[net.Conn]struct{}),
+		handlers: make(map[string]*handler),
+	}
+
+	s.wg.Add(1)
+	go s.accept()
+
+	return s, nil
+}
+
+// Host returns the host the server is listening on.
+func (s *Server) Host() string {
+	return s.ln.Addr().(*net.TCPAddr).IP.String()
+}
+
+// Port returns the port the server is listening on.
+func (s *Server) Port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+// Close stops the server and closes all connections to it.
+func (s *Server) Close() error {
+	err := s.ln.Close()
+
+	s.mu.Lock()
+	s.closed = true
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+// SetPassword requires clients to authenticate as user with password
+// using SCRAM-SHA-256. Without any passwords
+// clients connect without authenticating.
+func (s *Server) SetPassword(user, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.passwords == nil {
+		s.passwords = make(map[string]string)
+	}
+
+	s.passwords[user] = password
+}
+
+// Handle registers the responses to query.
+// Each execution of query consumes the next response,
+// the last response is repeated once the others are consumed.
+// Handle replaces any responses previously registered for query.
+func (s *Server) Handle(query string, responses ...Response) {
+	if len(responses) == 0 {
+		panic("edgedbtest: Handle requires at least one response")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handlers[query] = &handler{responses: responses}
+}
+
+// Queries returns the text of the queries and scripts
+// that clients executed in the order they were executed.
+func (s *Server) Queries() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queries := make([]string, len(s.queries))
+	copy(queries, s.queries)
+	return queries
+}
+
+func (s *Server) handler(query string) *handler {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.handlers[query]
+}
+
+func (s *Server) executed(query string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.queries = append(s.queries, query)
+}
+
+// authenticator returns a SCRAM server
+// or nil if clients do not need to authenticate.
+func (s *Server) authenticator() (*scram.Server, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.passwords) == 0 {
+		return nil, nil
+	}
+
+	passwords := make(map[string]string, len(s.passwords))
+	for user, password := range s.passwords {
+		passwords[user] = password
+	}
+
+	return scram.SHA256.NewServer(
+		func(user string) (scram.StoredCredentials, error) {
+			password, ok := passwords[user]
+			if !ok {
+				return scram.StoredCredentials{}, fmt.Errorf(
+					"edgedbtest: unknown user: %q", user,
+				)
+			}
+
+			client, err := scram.SHA256.NewClient(user, password, "")
+			if err != nil {
+				return scram.StoredCredentials{}, err
+			}
+
+			return client.GetStoredCredentials(scram.KeyFactors{
+				Salt:  string(s.salt),
+				Iters: 4096,
+			}), nil
+		},
+	)
+}
+
+func (s *Server) accept() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			_ = conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			newServerConn(s, conn).serve()
+
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+		}()
+	}
+}
@@ -0,0 +1,460 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedbtest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/edgedb/edgedb-go"
+	"github.com/edgedb/edgedb-go/edgedbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startServer(t *testing.T) *edgedbtest.Server {
+	server, err := edgedbtest.NewServer()
+	require.Nil(t, err)
+
+	return server
+}
+
+func connect(
+	ctx context.Context,
+	t *testing.T,
+	server *edgedbtest.Server,
+) *edgedb.Pool {
+	p, err := edgedb.Connect(ctx, options(server))
+	require.Nil(t, err)
+
+	return p
+}
+
+func options(server *edgedbtest.Server) edgedb.Options {
+	return edgedb.Options{
+		Hosts:    []string{server.Host()},
+		Ports:    []int{server.Port()},
+		User:     "edgedb",
+		Database: "edgedb",
+		MinConns: 1,
+		MaxConns: 1,
+	}
+}
+
+func TestQueryObjects(t *testing.T) {
+	server := startServer(t)
+	defer server.Close() // nolint:errcheck
+
+	user := edgedbtest.Object(
+		edgedbtest.Field{Name: "name", Type: edgedbtest.Str},
+		edgedbtest.Field{Name: "age", Type: edgedbtest.Int64},
+	)
+
+	query := "SELECT User { name, age }"
+	server.Handle(query, edgedbtest.Response{
+		Type: user,
+		Rows: []interface{}{
+			[]interface{}{"Alice", int64(21)},
+			[]interface{}{"Bob", nil},
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p := connect(ctx, t, server)
+	defer p.Close() // nolint:errcheck
+
+	type User struct {
+		Name string `edgedb:"name"`
+		Age  int64  `edgedb:"age"`
+	}
+
+	var users []User
+	require.Nil(t, p.Query(ctx, query, &users))
+	assert.Equal(t, []User{{"Alice", 21}, {"Bob", 0}}, users)
+
+	// the second query is executed optimistically
+	// with the cached type descriptors.
+	users = nil
+	require.Nil(t, p.Query(ctx, query, &users))
+	assert.Equal(t, []User{{"Alice", 21}, {"Bob", 0}}, users)
+
+	var one User
+	err := p.QueryOne(ctx, query, &one)
+
+	var edbErr edgedb.Error
+	require.True(t, errors.As(err, &edbErr), err)
+	assert.True(t, edbErr.Category(edgedb.CardinalityViolationError), err)
+
+	assert.Equal(t, []string{query, query, query}, server.Queries())
+}
+
+func TestQueryDescriptorDrift(t *testing.T) {
+	server := startServer(t)
+	defer server.Close() // nolint:errcheck
+
+	query := "SELECT User { name, age }"
+	server.Handle(
+		query,
+		edgedbtest.Response{
+			Type: edgedbtest.Object(
+				edgedbtest.Field{Name: "name", Type: edgedbtest.Str},
+			),
+			Rows: []interface{}{[]interface{}{"Alice"}},
+		},
+		// the schema changed and the query now returns age too.
+		edgedbtest.Response{
+			Type: edgedbtest.Object(
+				edgedbtest.Field{Name: "name", Type: edgedbtest.Str},
+				edgedbtest.Field{Name: "age", Type: edgedbtest.Int64},
+			),
+			Rows: []interface{}{[]interface{}{"Alice", int64(21)}},
+		},
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p := connect(ctx, t, server)
+	defer p.Close() // nolint:errcheck
+
+	type User struct {
+		Name string `edgedb:"name"`
+		Age  int64  `edgedb:"age"`
+	}
+
+	var users []User
+	require.Nil(t, p.Query(ctx, query, &users))
+	assert.Equal(t, []User{{"Alice", 0}}, users)
+
+	// the optimistic execute has stale type IDs,
+	// so the query is run again after it is described.
+	users = nil
+	require.Nil(t, p.Query(ctx, query, &users))
+	assert.Equal(t, []User{{"Alice", 21}}, users)
+
+	var user User
+	require.Nil(t, p.QueryOne(ctx, query, &user))
+	assert.Equal(t, User{"Alice", 21}, user)
+
+	assert.Equal(t, []string{query, query, query}, server.Queries())
+}
+
+func TestQueryIterDescriptorDrift(t *testing.T) {
+	server := startServer(t)
+	defer server.Close() // nolint:errcheck
+
+	query := "SELECT User { name, age }"
+	server.Handle(
+		query,
+		edgedbtest.Response{
+			Type: edgedbtest.Object(
+				edgedbtest.Field{Name: "name", Type: edgedbtest.Str},
+			),
+			Rows: []interface{}{[]interface{}{"Alice"}},
+		},
+		// the schema changed and the query now returns age too.
+		edgedbtest.Response{
+			Type: edgedbtest.Object(
+				edgedbtest.Field{Name: "name", Type: edgedbtest.Str},
+				edgedbtest.Field{Name: "age", Type: edgedbtest.Int64},
+			),
+			Rows: []interface{}{
+				[]interface{}{"Alice", int64(21)},
+				[]interface{}{"Bob", int64(32)},
+			},
+		},
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p := connect(ctx, t, server)
+	defer p.Close() // nolint:errcheck
+
+	type User struct {
+		Name string `edgedb:"name"`
+		Age  int64  `edgedb:"age"`
+	}
+
+	iterate := func() []User {
+		rows, err := p.QueryIter(ctx, query)
+		require.Nil(t, err)
+
+		var users []User
+		for rows.Next() {
+			var user User
+			require.Nil(t, rows.Scan(&user))
+			users = append(users, user)
+		}
+
+		require.Nil(t, rows.Close())
+		return users
+	}
+
+	assert.Equal(t, []User{{"Alice", 0}}, iterate())
+
+	// the optimistic execute has stale type IDs,
+	// so the query is sent again after it is described.
+	expected := []User{{"Alice", 21}, {"Bob", 32}}
+	assert.Equal(t, expected, iterate())
+	assert.Equal(t, expected, iterate())
+
+	assert.Equal(t, []string{query, query, query}, server.Queries())
+}
+
+func TestQueryNestedTypes(t *testing.T) {
+	server := startServer(t)
+	defer server.Close() // nolint:errcheck
+
+	query := "SELECT (1, (a := 'b'), [true], {<bytes>'c'})"
+	server.Handle(query, edgedbtest.Response{
+		Type: edgedbtest.Object(
+			edgedbtest.Field{
+				Name: "tuple",
+				Type: edgedbtest.Tuple(edgedbtest.Int32, edgedbtest.Float64),
+			},
+			edgedbtest.Field{
+				Name: "named",
+				Type: edgedbtest.NamedTuple(
+					edgedbtest.Field{Name: "a", Type: edgedbtest.Str},
+				),
+			},
+			edgedbtest.Field{
+				Name: "array",
+				Type: edgedbtest.Array(edgedbtest.Bool),
+			},
+			edgedbtest.Field{
+				Name: "set",
+				Type: edgedbtest.Set(edgedbtest.Bytes),
+			},
+		),
+		Rows: []interface{}{[]interface{}{
+			[]interface{}{int32(1), 2.5},
+			[]interface{}{"b"},
+			[]interface{}{true, false},
+			[]interface{}{[]byte("c")},
+		}},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p := connect(ctx, t, server)
+	defer p.Close() // nolint:errcheck
+
+	type Result struct {
+		Tuple struct {
+			First  int32   `edgedb:"0"`
+			Second float64 `edgedb:"1"`
+		} `edgedb:"tuple"`
+		Named struct {
+			A string `edgedb:"a"`
+		} `edgedb:"named"`
+		Array []bool   `edgedb:"array"`
+		Set   [][]byte `edgedb:"set"`
+	}
+
+	var result Result
+	require.Nil(t, p.QueryOne(ctx, query, &result))
+
+	var expected Result
+	expected.Tuple.First = 1
+	expected.Tuple.Second = 2.5
+	expected.Named.A = "b"
+	expected.Array = []bool{true, false}
+	expected.Set = [][]byte{[]byte("c")}
+	assert.Equal(t, expected, result)
+}
+
+func TestQueryArgs(t *testing.T) {
+	server := startServer(t)
+	defer server.Close() // nolint:errcheck
+
+	query := "SELECT <str>$0 ++ <str>$1"
+	server.Handle(query, edgedbtest.Response{
+		Args: edgedbtest.Tuple(edgedbtest.Str, edgedbtest.Str),
+		Type: edgedbtest.Str,
+		Rows: []interface{}{"ab"},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p := connect(ctx, t, server)
+	defer p.Close() // nolint:errcheck
+
+	var result string
+	require.Nil(t, p.QueryOne(ctx, query, &result, "a", "b"))
+	assert.Equal(t, "ab", result)
+
+	err := p.QueryOne(ctx, query, &result, "a")
+	var edbErr edgedb.Error
+	require.True(t, errors.As(err, &edbErr), err)
+	assert.True(t, edbErr.Category(edgedb.InvalidArgumentError), err)
+}
+
+func TestQueryJSON(t *testing.T) {
+	server := startServer(t)
+	defer server.Close() // nolint:errcheck
+
+	query := "SELECT User { name }"
+	server.Handle(query, edgedbtest.Response{
+		JSON: `[{"name": "Alice"}]`,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p := connect(ctx, t, server)
+	defer p.Close() // nolint:errcheck
+
+	var result []byte
+	require.Nil(t, p.QueryJSON(ctx, query, &result))
+	assert.Equal(t, `[{"name": "Alice"}]`, string(result))
+}
+
+func TestErrorResponse(t *testing.T) {
+	server := startServer(t)
+	defer server.Close() // nolint:errcheck
+
+	query := "INSERT User { name := 'Alice' }"
+	server.Handle(query, edgedbtest.Response{
+		Error: &edgedbtest.Error{
+			Code:    edgedbtest.ConstraintViolationError,
+			Message: "name violates exclusivity constraint",
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p := connect(ctx, t, server)
+	defer p.Close() // nolint:errcheck
+
+	var result []interface{}
+	err := p.Query(ctx, query, &result)
+
+	var edbErr edgedb.Error
+	require.True(t, errors.As(err, &edbErr), err)
+	assert.True(t, edbErr.Category(edgedb.ConstraintViolationError), err)
+	assert.EqualError(
+		t,
+		err,
+		"edgedb.ConstraintViolationError: "+
+			"name violates exclusivity constraint",
+	)
+
+	// the connection is still usable after an error.
+	err = p.Execute(ctx, query)
+	require.True(t, errors.As(err, &edbErr), err)
+	assert.True(t, edbErr.Category(edgedb.ConstraintViolationError), err)
+}
+
+func TestUnexpectedQuery(t *testing.T) {
+	server := startServer(t)
+	defer server.Close() // nolint:errcheck
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p := connect(ctx, t, server)
+	defer p.Close() // nolint:errcheck
+
+	var result []string
+	err := p.Query(ctx, "SELECT 'unknown'", &result)
+
+	var edbErr edgedb.Error
+	require.True(t, errors.As(err, &edbErr), err)
+	assert.True(t, edbErr.Category(edgedb.QueryError), err)
+	assert.Contains(t, err.Error(), `unexpected query: "SELECT 'unknown'"`)
+}
+
+func TestRetryingTxRetriesConflicts(t *testing.T) {
+	server := startServer(t)
+	defer server.Close() // nolint:errcheck
+
+	query := "UPDATE Counter SET { value := .value + 1 }"
+	server.Handle(
+		query,
+		edgedbtest.Response{Error: &edgedbtest.Error{
+			Code:    edgedbtest.TransactionSerializationError,
+			Message: "could not serialize access",
+		}},
+		edgedbtest.Response{
+			Type: edgedbtest.Int64,
+			Rows: []interface{}{int64(2)},
+		},
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p := connect(ctx, t, server)
+	defer p.Close() // nolint:errcheck
+
+	p = p.WithRetryOptions(edgedb.NewRetryOptions().WithDefault(
+		edgedb.NewRetryRule().WithBackoff(
+			func(int) time.Duration { return time.Millisecond },
+		),
+	))
+
+	var value int64
+	attempts := 0
+	err := p.RetryingTx(ctx, func(ctx context.Context, tx *edgedb.Tx) error {
+		attempts++
+		return tx.QueryOne(ctx, query, &value)
+	})
+	require.Nil(t, err)
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, int64(2), value)
+
+	queries := server.Queries()
+	require.Equal(t, 6, len(queries), queries)
+	assert.Contains(t, queries[0], "START TRANSACTION")
+	assert.Equal(t, query, queries[1])
+	assert.Equal(t, "ROLLBACK;", queries[2])
+	assert.Contains(t, queries[3], "START TRANSACTION")
+	assert.Equal(t, query, queries[4])
+	assert.Equal(t, "COMMIT;", queries[5])
+}
+
+func TestAuthentication(t *testing.T) {
+	server := startServer(t)
+	defer server.Close() // nolint:errcheck
+
+	server.SetPassword("edgedb", "secret")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	opts := options(server)
+	opts.Password = "secret"
+	p, err := edgedb.Connect(ctx, opts)
+	require.Nil(t, err)
+	require.Nil(t, p.Close())
+
+	opts.Password = "wrong"
+	_, err = edgedb.Connect(ctx, opts)
+
+	var edbErr edgedb.Error
+	require.True(t, errors.As(err, &edbErr), err)
+	assert.True(t, edbErr.Category(edgedb.AuthenticationError), err)
+}
@@ -0,0 +1,531 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedbtest
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math"
+
+	"github.com/edgedb/edgedb-go/internal/buff"
+	"github.com/edgedb/edgedb-go/internal/descriptor"
+	types "github.com/edgedb/edgedb-go/internal/edgedbtypes"
+)
+
+// Type describes the shape of a query's results.
+// It is sent to clients as a type descriptor
+// and determines how the values in Response.Rows are encoded.
+type Type interface {
+	// id returns the type descriptor ID.
+	id() types.UUID
+
+	// describe appends the descriptors of the type and its children to d
+	// and returns the position of the type's descriptor.
+	describe(d *descriptors) uint16
+
+	// encode writes the data representation of val.
+	encode(w *buff.Writer, val interface{}) error
+}
+
+// descriptors builds a type descriptor message body.
+// Each descriptor is only written once.
+type descriptors struct {
+	w         *buff.Writer
+	positions map[types.UUID]uint16
+}
+
+// add returns the position of the descriptor with the given id,
+// writing it with write if it has not been written yet.
+func (d *descriptors) add(id types.UUID, write func()) uint16 {
+	if pos, ok := d.positions[id]; ok {
+		return pos
+	}
+
+	write()
+	pos := uint16(len(d.positions))
+	d.positions[id] = pos
+	return pos
+}
+
+// writeDescriptor writes the type descriptor of typ.
+// nil describes a query that does not return any data.
+func writeDescriptor(w *buff.Writer, typ Type) {
+	if typ == nil {
+		w.PushUint32(0) // no descriptor
+		return
+	}
+
+	w.BeginBytes()
+	typ.describe(&descriptors{w: w, positions: map[types.UUID]uint16{}})
+	w.EndBytes()
+}
+
+// descriptorID returns the type descriptor ID of typ.
+func descriptorID(typ Type) types.UUID {
+	if typ == nil {
+		return descriptor.IDZero
+	}
+
+	return typ.id()
+}
+
+// hashID derives a stable descriptor ID
+// for a non scalar type from its contents.
+func hashID(kind descriptor.Type, parts ...interface{}) types.UUID {
+	h := sha256.New()
+	fmt.Fprint(h, kind)
+	for _, part := range parts {
+		fmt.Fprintf(h, "|%v", part)
+	}
+
+	var id types.UUID
+	copy(id[:], h.Sum(nil))
+	return id
+}
+
+type scalar struct {
+	name     string
+	uuid     types.UUID
+	encodeFn func(w *buff.Writer, val interface{}) bool
+}
+
+func (s *scalar) id() types.UUID { return s.uuid }
+
+func (s *scalar) describe(d *descriptors) uint16 {
+	return d.add(s.uuid, func() {
+		d.w.PushUint8(uint8(descriptor.BaseScalar))
+		d.w.PushUUID(s.uuid)
+	})
+}
+
+func (s *scalar) encode(w *buff.Writer, val interface{}) error {
+	w.BeginBytes()
+	if !s.encodeFn(w, val) {
+		return fmt.Errorf(
+			"edgedbtest: unexpected value for %v: %T", s.name, val,
+		)
+	}
+	w.EndBytes()
+
+	return nil
+}
+
+func scalarID(n uint8) types.UUID {
+	return types.UUID{14: 1, 15: n}
+}
+
+// The supported scalar types.
+// Rows use the Go type that the client decodes the scalar into.
+var (
+	// UUID is std::uuid, its values are [16]byte.
+	UUID Type = &scalar{"std::uuid", scalarID(0x00), encodeUUID}
+
+	// Str is std::str, its values are strings.
+	Str Type = &scalar{"std::str", scalarID(0x01), encodeStr}
+
+	// Bytes is std::bytes, its values are []byte.
+	Bytes Type = &scalar{"std::bytes", scalarID(0x02), encodeBytes}
+
+	// Int16 is std::int16, its values are int16.
+	Int16 Type = &scalar{"std::int16", scalarID(0x03), encodeInt16}
+
+	// Int32 is std::int32, its values are int32.
+	Int32 Type = &scalar{"std::int32", scalarID(0x04), encodeInt32}
+
+	// Int64 is std::int64, its values are int64.
+	Int64 Type = &scalar{"std::int64", scalarID(0x05), encodeInt64}
+
+	// Float32 is std::float32, its values are float32.
+	Float32 Type = &scalar{"std::float32", scalarID(0x06), encodeFloat32}
+
+	// Float64 is std::float64, its values are float64.
+	Float64 Type = &scalar{"std::float64", scalarID(0x07), encodeFloat64}
+
+	// Bool is std::bool, its values are bool.
+	Bool Type = &scalar{"std::bool", scalarID(0x09), encodeBool}
+
+	// JSON is std::json, its values are []byte of encoded JSON.
+	JSON Type = &scalar{"std::json", scalarID(0x0f), encodeJSON}
+)
+
+func encodeUUID(w *buff.Writer, val interface{}) bool {
+	switch v := val.(type) {
+	case types.UUID:
+		w.PushUUID(v)
+	case [16]byte:
+		w.PushUUID(v)
+	default:
+		return false
+	}
+
+	return true
+}
+
+func encodeStr(w *buff.Writer, val interface{}) bool {
+	v, ok := val.(string)
+	if ok {
+		w.PushBytes([]byte(v))
+	}
+
+	return ok
+}
+
+func encodeBytes(w *buff.Writer, val interface{}) bool {
+	v, ok := val.([]byte)
+	if ok {
+		w.PushBytes(v)
+	}
+
+	return ok
+}
+
+func encodeInt16(w *buff.Writer, val interface{}) bool {
+	v, ok := val.(int16)
+	if ok {
+		w.PushUint16(uint16(v))
+	}
+
+	return ok
+}
+
+func encodeInt32(w *buff.Writer, val interface{}) bool {
+	v, ok := val.(int32)
+	if ok {
+		w.PushUint32(uint32(v))
+	}
+
+	return ok
+}
+
+func encodeInt64(w *buff.Writer, val interface{}) bool {
+	v, ok := val.(int64)
+	if ok {
+		w.PushUint64(uint64(v))
+	}
+
+	return ok
+}
+
+func encodeFloat32(w *buff.Writer, val interface{}) bool {
+	v, ok := val.(float32)
+	if ok {
+		w.PushUint32(math.Float32bits(v))
+	}
+
+	return ok
+}
+
+func encodeFloat64(w *buff.Writer, val interface{}) bool {
+	v, ok := val.(float64)
+	if ok {
+		w.PushUint64(math.Float64bits(v))
+	}
+
+	return ok
+}
+
+func encodeBool(w *buff.Writer, val interface{}) bool {
+	v, ok := val.(bool)
+	if ok {
+		if v {
+			w.PushUint8(1)
+		} else {
+			w.PushUint8(0)
+		}
+	}
+
+	return ok
+}
+
+func encodeJSON(w *buff.Writer, val interface{}) bool {
+	v, ok := val.([]byte)
+	if ok {
+		// json format is always 1
+		// https://www.edgedb.com/docs/internals/protocol/dataformats#std-json
+		w.PushUint8(1)
+		w.PushBytes(v)
+	}
+
+	return ok
+}
+
+// Field is an element of an object or a named tuple.
+type Field struct {
+	Name string
+	Type Type
+}
+
+// encodeElements encodes the elements of an object or a tuple.
+// A nil element of an object is a missing value.
+func encodeElements(
+	w *buff.Writer,
+	name string,
+	elements []Type,
+	val interface{},
+	nullable bool,
+) error {
+	values, ok := val.([]interface{})
+	if !ok {
+		return fmt.Errorf(
+			"edgedbtest: unexpected value for %v: %T", name, val,
+		)
+	}
+
+	if len(values) != len(elements) {
+		return fmt.Errorf(
+			"edgedbtest: expected %v %v elements, got %v",
+			len(elements), name, len(values),
+		)
+	}
+
+	w.BeginBytes()
+	w.PushUint32(uint32(len(values)))
+	for i, v := range values {
+		w.PushUint32(0) // reserved
+
+		if v == nil && nullable {
+			w.PushUint32(0xffffffff) // missing value
+			continue
+		}
+
+		if err := elements[i].encode(w, v); err != nil {
+			return err
+		}
+	}
+	w.EndBytes()
+
+	return nil
+}
+
+type object struct {
+	uuid   types.UUID
+	fields []Field
+}
+
+// Object returns an object type with the given fields.
+// Its values are []interface{} with one value for each field,
+// a nil value is a missing field.
+func Object(fields ...Field) Type {
+	parts := make([]interface{}, 0, 2*len(fields))
+	for _, f := range fields {
+		parts = append(parts, f.Name, f.Type.id())
+	}
+
+	return &object{hashID(descriptor.Object, parts...), fields}
+}
+
+func (o *object) id() types.UUID { return o.uuid }
+
+func (o *object) describe(d *descriptors) uint16 {
+	positions := make([]uint16, len(o.fields))
+	for i, f := range o.fields {
+		positions[i] = f.Type.describe(d)
+	}
+
+	return d.add(o.uuid, func() {
+		d.w.PushUint8(uint8(descriptor.Object))
+		d.w.PushUUID(o.uuid)
+		d.w.PushUint16(uint16(len(o.fields)))
+		for i, f := range o.fields {
+			d.w.PushUint8(0) // flags
+			d.w.PushString(f.Name)
+			d.w.PushUint16(positions[i])
+		}
+	})
+}
+
+func (o *object) encode(w *buff.Writer, val interface{}) error {
+	elements := make([]Type, len(o.fields))
+	for i, f := range o.fields {
+		elements[i] = f.Type
+	}
+
+	return encodeElements(w, "object", elements, val, true)
+}
+
+type tuple struct {
+	uuid     types.UUID
+	elements []Type
+}
+
+// Tuple returns a tuple type with the given element types.
+// Its values are []interface{} with one value for each element.
+func Tuple(elements ...Type) Type {
+	parts := make([]interface{}, len(elements))
+	for i, e := range elements {
+		parts[i] = e.id()
+	}
+
+	return &tuple{hashID(descriptor.Tuple, parts...), elements}
+}
+
+func (t *tuple) id() types.UUID { return t.uuid }
+
+func (t *tuple) describe(d *descriptors) uint16 {
+	positions := make([]uint16, len(t.elements))
+	for i, e := range t.elements {
+		positions[i] = e.describe(d)
+	}
+
+	return d.add(t.uuid, func() {
+		d.w.PushUint8(uint8(descriptor.Tuple))
+		d.w.PushUUID(t.uuid)
+		d.w.PushUint16(uint16(len(t.elements)))
+		for _, pos := range positions {
+			d.w.PushUint16(pos)
+		}
+	})
+}
+
+func (t *tuple) encode(w *buff.Writer, val interface{}) error {
+	return encodeElements(w, "tuple", t.elements, val, false)
+}
+
+type namedTuple struct {
+	uuid   types.UUID
+	fields []Field
+}
+
+// NamedTuple returns a named tuple type with the given fields.
+// Its values are []interface{} with one value for each field.
+func NamedTuple(fields ...Field) Type {
+	parts := make([]interface{}, 0, 2*len(fields))
+	for _, f := range fields {
+		parts = append(parts, f.Name, f.Type.id())
+	}
+
+	return &namedTuple{hashID(descriptor.NamedTuple, parts...), fields}
+}
+
+func (t *namedTuple) id() types.UUID { return t.uuid }
+
+func (t *namedTuple) describe(d *descriptors) uint16 {
+	positions := make([]uint16, len(t.fields))
+	for i, f := range t.fields {
+		positions[i] = f.Type.describe(d)
+	}
+
+	return d.add(t.uuid, func() {
+		d.w.PushUint8(uint8(descriptor.NamedTuple))
+		d.w.PushUUID(t.uuid)
+		d.w.PushUint16(uint16(len(t.fields)))
+		for i, f := range t.fields {
+			d.w.PushString(f.Name)
+			d.w.PushUint16(positions[i])
+		}
+	})
+}
+
+func (t *namedTuple) encode(w *buff.Writer, val interface{}) error {
+	elements := make([]Type, len(t.fields))
+	for i, f := range t.fields {
+		elements[i] = f.Type
+	}
+
+	return encodeElements(w, "named tuple", elements, val, false)
+}
+
+// collection is an array or a set.
+type collection struct {
+	kind    descriptor.Type
+	uuid    types.UUID
+	element Type
+}
+
+// Array returns an array type with the given element type.
+// Its values are []interface{}.
+func Array(element Type) Type {
+	return &collection{
+		descriptor.Array,
+		hashID(descriptor.Array, element.id()),
+		element,
+	}
+}
+
+// Set returns a set type with the given element type.
+// Sets are the type of multi links and properties of objects.
+// Its values are []interface{}.
+func Set(element Type) Type {
+	return &collection{
+		descriptor.Set,
+		hashID(descriptor.Set, element.id()),
+		element,
+	}
+}
+
+func (c *collection) id() types.UUID { return c.uuid }
+
+func (c *collection) describe(d *descriptors) uint16 {
+	pos := c.element.describe(d)
+
+	return d.add(c.uuid, func() {
+		d.w.PushUint8(uint8(c.kind))
+		d.w.PushUUID(c.uuid)
+		d.w.PushUint16(pos)
+
+		if c.kind == descriptor.Array {
+			d.w.PushUint16(1)          // number of dimensions
+			d.w.PushUint32(0xffffffff) // unbounded dimension
+		}
+	})
+}
+
+func (c *collection) encode(w *buff.Writer, val interface{}) error {
+	values, ok := val.([]interface{})
+	if !ok {
+		return fmt.Errorf(
+			"edgedbtest: unexpected value for array or set: %T", val,
+		)
+	}
+
+	w.BeginBytes()
+	if len(values) == 0 {
+		w.PushUint32(0) // number of dimensions
+		w.PushUint64(0) // reserved
+		w.EndBytes()
+		return nil
+	}
+
+	w.PushUint32(1)                   // number of dimensions
+	w.PushUint64(0)                   // reserved
+	w.PushUint32(uint32(len(values))) // upper bound
+	w.PushUint32(1)                   // lower bound
+
+	_, isSetOfArrays := c.element.(*collection)
+	isSetOfArrays = isSetOfArrays && c.kind == descriptor.Set &&
+		c.element.(*collection).kind == descriptor.Array
+
+	for _, v := range values {
+		if isSetOfArrays {
+			// arrays in sets are wrapped in an envelope.
+			w.BeginBytes()
+			w.PushUint32(1) // number of elements
+			w.PushUint32(0) // reserved
+		}
+
+		if err := c.element.encode(w, v); err != nil {
+			return err
+		}
+
+		if isSetOfArrays {
+			w.EndBytes()
+		}
+	}
+	w.EndBytes()
+
+	return nil
+}
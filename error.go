@@ -28,6 +28,10 @@ import (
 
 var errZeroResults error = &noDataError{msg: "zero results"}
 
+// errNotExecuted is returned by decodeData
+// when the server described the query instead of running it.
+var errNotExecuted = errors.New("query was described but not executed")
+
 // ErrorTag is the argument type to Error.HasTag().
 type ErrorTag string
 
@@ -36,13 +36,32 @@ func (c *baseConn) fallThrough(r *buff.Reader) error {
 	case message.ParameterStatus:
 		name := r.PopString()
 		value := r.PopString()
-		c.cfg.serverSettings[name] = value
+		c.serverSettings.set(name, value)
+	case message.StateDataDescription:
+		desc, err := c.popDescriptor(r, r.PopUUID())
+		if err != nil {
+			return err
+		}
+
+		c.stateDesc = desc
 	case message.LogMessage:
 		severity := logMsgSeverityLookup[r.PopUint8()]
 		code := r.PopUint32()
 		message := r.PopString()
-		ignoreHeaders(r)
-		log.Println("SERVER MESSAGE", severity, code, message)
+
+		if c.cfg.onServerMessage == nil {
+			ignoreHeaders(r)
+			log.Println("SERVER MESSAGE", severity, code, message)
+			break
+		}
+
+		n := int(r.PopUint16())
+		attrs := make(map[uint16]string, n)
+		for i := 0; i < n; i++ {
+			attrs[r.PopUint16()] = r.PopString()
+		}
+
+		c.cfg.onServerMessage(severity, code, message, attrs)
 	default:
 		msg := fmt.Sprintf("unexpected message type: 0x%x", r.MsgType)
 		return &unexpectedMessageError{msg: msg}
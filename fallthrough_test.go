@@ -0,0 +1,118 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedb
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/edgedb/edgedb-go/internal/buff"
+	"github.com/edgedb/edgedb-go/internal/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// messageReader returns a reader positioned at the start
+// of a single message's payload.
+func messageReader(mType uint8, write func(*buff.Writer)) *buff.Reader {
+	w := buff.NewWriter(nil)
+	w.BeginMessage(mType)
+	write(w)
+	w.EndMessage()
+
+	var data bytes.Buffer
+	if err := w.Send(&data); err != nil {
+		panic(err)
+	}
+
+	r := buff.SimpleReader(data.Bytes()[5:])
+	r.MsgType = mType
+	return r
+}
+
+func TestFallThroughLogMessage(t *testing.T) {
+	type logMessage struct {
+		severity string
+		code     uint32
+		text     string
+		attrs    map[uint16]string
+	}
+
+	var received []logMessage
+	handler := func(
+		severity string,
+		code uint32,
+		text string,
+		attrs map[uint16]string,
+	) {
+		received = append(received, logMessage{severity, code, text, attrs})
+	}
+
+	c := &baseConn{cfg: &connConfig{onServerMessage: handler}}
+	r := messageReader(message.LogMessage, func(w *buff.Writer) {
+		w.PushUint8(0x50) // severity
+		w.PushUint32(0xf0_00_00_00)
+		w.PushString("the message")
+		w.PushUint16(1) // header count
+		w.PushUint16(0x0001)
+		w.PushString("a hint")
+	})
+
+	require.Nil(t, c.fallThrough(r))
+	assert.Empty(t, r.Buf)
+
+	expected := []logMessage{{
+		severity: "WARNING",
+		code:     0xf0_00_00_00,
+		text:     "the message",
+		attrs:    map[uint16]string{0x0001: "a hint"},
+	}}
+	assert.Equal(t, expected, received)
+}
+
+func TestFallThroughParameterStatus(t *testing.T) {
+	settings := newSettingsStore()
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			c := &baseConn{cfg: &connConfig{}, serverSettings: settings}
+			r := messageReader(message.ParameterStatus, func(w *buff.Writer) {
+				w.PushString(fmt.Sprintf("name%v", i))
+				w.PushString(fmt.Sprintf("value%v", i))
+			})
+
+			assert.Nil(t, c.fallThrough(r))
+			settings.copy()
+		}(i)
+	}
+
+	wg.Wait()
+
+	result := settings.copy()
+	assert.Equal(t, 10, len(result))
+	assert.Equal(t, "value3", result["name3"])
+
+	// the returned map is a copy
+	result["name3"] = "changed"
+	assert.Equal(t, "value3", settings.copy()["name3"])
+}
@@ -35,6 +35,8 @@ const (
 	TypeSpecNotFoundError                  ErrorCategory = "errors::TypeSpecNotFoundError"
 	UnexpectedMessageError                 ErrorCategory = "errors::UnexpectedMessageError"
 	InputDataError                         ErrorCategory = "errors::InputDataError"
+	ParameterTypeMismatchError             ErrorCategory = "errors::ParameterTypeMismatchError"
+	StateMismatchError                     ErrorCategory = "errors::StateMismatchError"
 	ResultCardinalityMismatchError         ErrorCategory = "errors::ResultCardinalityMismatchError"
 	CapabilityError                        ErrorCategory = "errors::CapabilityError"
 	UnsupportedCapabilityError             ErrorCategory = "errors::UnsupportedCapabilityError"
@@ -397,6 +399,88 @@ func (e *inputDataError) HasTag(tag ErrorTag) bool {
 	}
 }
 
+type parameterTypeMismatchError struct {
+	msg string
+	err error
+}
+
+func (e *parameterTypeMismatchError) Error() string {
+	msg := e.msg
+	if e.err != nil {
+		msg = e.err.Error()
+	}
+
+	return "edgedb.ParameterTypeMismatchError: " + msg
+}
+
+func (e *parameterTypeMismatchError) Unwrap() error { return e.err }
+
+func (e *parameterTypeMismatchError) Category(c ErrorCategory) bool {
+	switch c {
+	case ParameterTypeMismatchError:
+		return true
+	case InputDataError:
+		return true
+	case ProtocolError:
+		return true
+	default:
+		return false
+	}
+}
+
+func (e *parameterTypeMismatchError) isEdgeDBInputDataError() {}
+
+func (e *parameterTypeMismatchError) isEdgeDBProtocolError() {}
+
+func (e *parameterTypeMismatchError) HasTag(tag ErrorTag) bool {
+	switch tag {
+	default:
+		return false
+	}
+}
+
+type stateMismatchError struct {
+	msg string
+	err error
+}
+
+func (e *stateMismatchError) Error() string {
+	msg := e.msg
+	if e.err != nil {
+		msg = e.err.Error()
+	}
+
+	return "edgedb.StateMismatchError: " + msg
+}
+
+func (e *stateMismatchError) Unwrap() error { return e.err }
+
+func (e *stateMismatchError) Category(c ErrorCategory) bool {
+	switch c {
+	case StateMismatchError:
+		return true
+	case InputDataError:
+		return true
+	case ProtocolError:
+		return true
+	default:
+		return false
+	}
+}
+
+func (e *stateMismatchError) isEdgeDBInputDataError() {}
+
+func (e *stateMismatchError) isEdgeDBProtocolError() {}
+
+func (e *stateMismatchError) HasTag(tag ErrorTag) bool {
+	switch tag {
+	case ShouldRetry:
+		return true
+	default:
+		return false
+	}
+}
+
 type resultCardinalityMismatchError struct {
 	msg string
 	err error
@@ -3325,6 +3409,10 @@ func errorFromCode(code uint32, msg string) error {
 		return &unexpectedMessageError{msg: msg}
 	case 0x03_02_00_00:
 		return &inputDataError{msg: msg}
+	case 0x03_02_01_00:
+		return &parameterTypeMismatchError{msg: msg}
+	case 0x03_02_02_00:
+		return &stateMismatchError{msg: msg}
 	case 0x03_03_00_00:
 		return &resultCardinalityMismatchError{msg: msg}
 	case 0x03_04_00_00:
@@ -31,82 +31,137 @@ import (
 	"github.com/edgedb/edgedb-go/internal/message"
 )
 
-func (c *baseConn) granularFlow(r *buff.Reader, q *gfQuery) (err error) {
-	ids, ok := c.getTypeIDs(q)
+func (c *baseConn) granularFlow(r *buff.Reader, q *gfQuery) error {
+	if c.protocolVersion.gte(protocolVersion1p0) {
+		return c.granularFlow1pX(r, q)
+	}
+
+	if len(q.state) > 0 {
+		return errStateNotSupported
+	}
+
+	cdcs, ok, err := c.cachedCodecs(q)
+	if err != nil {
+		return err
+	}
+
 	if !ok {
 		return c.pesimistic(r, q)
 	}
 
+	return c.optimistic(r, q, cdcs)
+}
+
+// cachedCodecs returns the codecs for q if the query's type IDs
+// and descriptors are cached.
+func (c *baseConn) cachedCodecs(q *gfQuery) (codecPair, bool, error) {
+	ids, ok := c.getTypeIDs(q)
+	if !ok {
+		return codecPair{}, false, nil
+	}
+
 	in, ok := c.inCodecCache.Get(ids.in)
 	if !ok {
-		if desc, OK := descCache.Get(ids.in); OK {
-			in, err = codecs.BuildEncoder(desc.(descriptor.Descriptor))
-			if err != nil {
-				return &unsupportedFeatureError{msg: err.Error()}
+		desc, OK := descCache.Get(ids.in)
+		if !OK {
+			return codecPair{}, false, nil
+		}
+
+		var err error
+		in, err = codecs.BuildEncoder(desc.(descriptor.Descriptor))
+		if err != nil {
+			return codecPair{}, false, &unsupportedFeatureError{
+				msg: err.Error(),
 			}
-		} else {
-			return c.pesimistic(r, q)
 		}
 	}
 
-	cOut, ok := c.outCodecCache.Get(codecKey{ID: ids.out, Type: q.outType})
+	out, ok := c.outCodecCache.Get(codecKey{ID: ids.out, Type: q.outType})
 	if !ok {
-		if desc, ok := descCache.Get(ids.out); ok {
-			d := desc.(descriptor.Descriptor)
-			path := codecs.Path(q.outType.String())
-			cOut, err = codecs.BuildDecoder(d, q.outType, path)
-			if err != nil {
-				err = fmt.Errorf(
-					"the \"out\" argument does not match query schema: %v",
-					err,
-				)
-				return &unsupportedFeatureError{msg: err.Error()}
-			}
-		} else {
-			return c.pesimistic(r, q)
+		desc, OK := descCache.Get(ids.out)
+		if !OK {
+			return codecPair{}, false, nil
+		}
+
+		var err error
+		out, err = c.buildDecoder(q, desc.(descriptor.Descriptor))
+		if err != nil {
+			return codecPair{}, false, err
 		}
 	}
 
-	cdsc := codecPair{in: in.(codecs.Encoder), out: cOut.(codecs.Decoder)}
-	return c.optimistic(r, q, cdsc)
+	cdcs := codecPair{in: in.(codecs.Encoder), out: out.(codecs.Decoder)}
+	return cdcs, true, nil
 }
 
-func (c *baseConn) pesimistic(r *buff.Reader, q *gfQuery) error {
-	ids, err := c.prepare(r, q)
-	if err != nil {
-		return err
+// buildDecoder builds a decoder for q's results.
+func (c *baseConn) buildDecoder(
+	q *gfQuery,
+	desc descriptor.Descriptor,
+) (codecs.Decoder, error) {
+	if q.fmt == format.JSON {
+		return codecs.JSONBytes, nil
 	}
-	c.putTypeIDs(q, ids)
 
-	descs, err := c.describe(r, q)
+	path := codecs.Path(q.outType.String())
+	decoder, err := codecs.BuildDecoder(desc, q.outType, path)
 	if err != nil {
-		return err
+		err = fmt.Errorf(
+			"the \"out\" argument does not match query schema: %v",
+			err,
+		)
+		return nil, &unsupportedFeatureError{msg: err.Error()}
 	}
+
+	return decoder, nil
+}
+
+// buildCodecs builds and caches the codecs for q.
+func (c *baseConn) buildCodecs(
+	q *gfQuery,
+	ids idPair,
+	descs descPair,
+) (codecPair, error) {
+	c.putTypeIDs(q, ids)
 	descCache.Put(ids.in, descs.in)
 	descCache.Put(ids.out, descs.out)
 
-	var cdcs codecPair
+	var (
+		cdcs codecPair
+		err  error
+	)
+
 	cdcs.in, err = codecs.BuildEncoder(descs.in)
 	if err != nil {
-		return &unsupportedFeatureError{msg: err.Error()}
+		return codecPair{}, &unsupportedFeatureError{msg: err.Error()}
 	}
 
-	if q.fmt == format.JSON {
-		cdcs.out = codecs.JSONBytes
-	} else {
-		path := codecs.Path(q.outType.String())
-		cdcs.out, err = codecs.BuildDecoder(descs.out, q.outType, path)
-		if err != nil {
-			err = fmt.Errorf(
-				"the \"out\" argument does not match query schema: %v",
-				err,
-			)
-			return &unsupportedFeatureError{msg: err.Error()}
-		}
+	cdcs.out, err = c.buildDecoder(q, descs.out)
+	if err != nil {
+		return codecPair{}, err
 	}
 
 	c.inCodecCache.Put(ids.in, cdcs.in)
 	c.outCodecCache.Put(codecKey{ID: ids.out, Type: q.outType}, cdcs.out)
+	return cdcs, nil
+}
+
+func (c *baseConn) pesimistic(r *buff.Reader, q *gfQuery) error {
+	ids, err := c.prepare(r, q)
+	if err != nil {
+		return err
+	}
+
+	descs, err := c.describe(r, q)
+	if err != nil {
+		return err
+	}
+
+	cdcs, err := c.buildCodecs(q, ids, descs)
+	if err != nil {
+		return err
+	}
+
 	return c.execute(r, q, cdcs)
 }
 
@@ -189,32 +244,9 @@ func (c *baseConn) describe(r *buff.Reader, q *gfQuery) (descPair, error) {
 	for r.Next(done.Chan) {
 		switch r.MsgType {
 		case message.CommandDataDescription:
-			ignoreHeaders(r)
-			card := r.PopUint8()
-			// input descriptor ID
-			r.Discard(16)
-
-			// input descriptor
-			descs.in = descriptor.Pop(r.PopSlice(r.PopUint32()))
-
-			// output descriptor ID
-			outID := r.PopUUID()
-
-			if outID == descriptor.IDZero {
-				r.Discard(4) // data length is always 0 for nil descriptor
-				descs.out = descriptor.Descriptor{ID: descriptor.IDZero}
-			} else {
-				descs.out = descriptor.Pop(r.PopSlice(r.PopUint32()))
-			}
-
-			if q.expCard == cardinality.One && card == cardinality.Many {
-				err = &resultCardinalityMismatchError{msg: fmt.Sprintf(
-					"the query has cardinality %v "+
-						"which does not match the expected cardinality %v",
-					cardinality.ToStr[card],
-					cardinality.ToStr[q.expCard],
-				)}
-			}
+			var e error
+			_, descs, e = c.decodeCommandDataDescription(r, q)
+			err = wrapAll(err, e)
 		case message.ReadyForCommand:
 			ignoreHeaders(r)
 			r.Discard(1) // transaction state
@@ -236,12 +268,104 @@ func (c *baseConn) describe(r *buff.Reader, q *gfQuery) (descPair, error) {
 	return descs, err
 }
 
+// decodeCommandDataDescription decodes a CommandDataDescription message.
+func (c *baseConn) decodeCommandDataDescription(
+	r *buff.Reader,
+	q *gfQuery,
+) (idPair, descPair, error) {
+	ignoreHeaders(r)
+	if c.protocolVersion.gte(protocolVersion1p0) {
+		r.PopUint64() // capabilities
+	}
+
+	card := r.PopUint8()
+
+	var (
+		ids   idPair
+		descs descPair
+	)
+
+	descs.card = card
+	ids.in = r.PopUUID()
+	in, err := c.popDescriptor(r, ids.in)
+	if err != nil {
+		r.DiscardMessage()
+		return ids, descs, err
+	}
+
+	ids.out = r.PopUUID()
+	out, err := c.popDescriptor(r, ids.out)
+	if err != nil {
+		return ids, descs, err
+	}
+
+	descs.in = in
+	descs.out = out
+
+	if q.expCard == cardinality.One &&
+		(card == cardinality.Many || card == cardinality.AtLeastOne) {
+		return ids, descs, &resultCardinalityMismatchError{msg: fmt.Sprintf(
+			"the query has cardinality %v "+
+				"which does not match the expected cardinality %v",
+			cardinality.ToStr[card],
+			cardinality.ToStr[q.expCard],
+		)}
+	}
+
+	return ids, descs, nil
+}
+
+// popDescriptor pops a type descriptor with the given ID.
+func (c *baseConn) popDescriptor(
+	r *buff.Reader,
+	id UUID,
+) (descriptor.Descriptor, error) {
+	if id == descriptor.IDZero {
+		r.Discard(4) // data length is always 0 for nil descriptor
+		return descriptor.Descriptor{ID: descriptor.IDZero}, nil
+	}
+
+	desc, err := descriptor.Pop(
+		r.PopSlice(r.PopUint32()),
+		c.protocolVersion.major,
+	)
+	if err != nil {
+		return descriptor.Descriptor{}, &unexpectedMessageError{
+			msg: err.Error(),
+		}
+	}
+
+	return desc, nil
+}
+
+// decodeCommandComplete decodes a CommandComplete message.
+func (c *baseConn) decodeCommandComplete(r *buff.Reader) {
+	ignoreHeaders(r)
+	if c.protocolVersion.gte(protocolVersion1p0) {
+		r.PopUint64() // capabilities
+		r.PopBytes()  // command status
+		r.PopUUID()   // state descriptor ID
+		r.PopBytes()  // state data
+		return
+	}
+
+	r.PopBytes() // command status
+}
+
 func (c *baseConn) execute(r *buff.Reader, q *gfQuery, cdcs codecPair) error {
+	if e := c.sendExecute(q, cdcs.in); e != nil {
+		return e
+	}
+
+	return c.decodeData(r, q, cdcs.out)
+}
+
+func (c *baseConn) sendExecute(q *gfQuery, in codecs.Encoder) error {
 	w := buff.NewWriter(c.writeMemory[:0])
-	w.BeginMessage(message.Execute)
+	w.BeginMessage(message.Execute0pX)
 	writeHeaders(w, q.headers)
 	w.PushUint32(0) // no statement name
-	if e := cdcs.in.Encode(w, q.args, codecs.Path("args")); e != nil {
+	if e := in.Encode(w, q.args, codecs.Path("args")); e != nil {
 		return &invalidArgumentError{msg: e.Error()}
 	}
 	w.EndMessage()
@@ -253,76 +377,42 @@ func (c *baseConn) execute(r *buff.Reader, q *gfQuery, cdcs codecPair) error {
 		return &clientConnectionError{err: e}
 	}
 
-	tmp := q.out
-	err := error(nil)
-	if q.expCard == cardinality.One {
-		err = errZeroResults
-	}
-	done := buff.NewSignal()
-
-	for r.Next(done.Chan) {
-		switch r.MsgType {
-		case message.Data:
-			elmCount := r.PopUint16()
-			if elmCount != 1 {
-				panic(fmt.Sprintf(
-					"unexpected number of elements: expected 1, got %v",
-					elmCount,
-				))
-			}
-			elmLen := r.PopUint32()
-
-			if !q.flat() {
-				val := reflect.New(q.outType).Elem()
-				s := r.PopSlice(elmLen)
-				cdcs.out.Decode(s, unsafe.Pointer(val.UnsafeAddr()))
-				tmp = reflect.Append(tmp, val)
-			} else {
-				cdcs.out.Decode(
-					r.PopSlice(elmLen),
-					unsafe.Pointer(q.out.UnsafeAddr()),
-				)
-			}
+	return nil
+}
 
-			if err == errZeroResults {
-				err = nil
-			}
-		case message.CommandComplete:
-			ignoreHeaders(r)
-			r.PopBytes() // command status
-		case message.ReadyForCommand:
-			ignoreHeaders(r)
-			r.Discard(1) // transaction state
-			done.Signal()
-		case message.ErrorResponse:
-			if err == errZeroResults {
-				err = nil
-			}
+func (c *baseConn) optimistic(
+	r *buff.Reader,
+	q *gfQuery,
+	cdcs codecPair,
+) error {
+	ids := idPair{in: cdcs.in.DescriptorID(), out: cdcs.out.DescriptorID()}
+	if e := c.sendOptimistic(q, cdcs.in, ids); e != nil {
+		return e
+	}
 
-			err = wrapAll(err, decodeError(r, q.cmd))
-		default:
-			if e := c.fallThrough(r); e != nil {
-				// the connection will not be usable after this x_x
-				return e
-			}
-		}
+	err := c.decodeData(r, q, cdcs.out)
+	if err != errNotExecuted {
+		return err
 	}
 
-	if r.Err != nil {
-		return &clientConnectionError{err: r.Err}
+	// The query's types changed so the server described it
+	// instead of running it. It is run again with the new codecs.
+	cdcs, ok, err := c.cachedCodecs(q)
+	if err != nil {
+		return err
 	}
 
-	if !q.flat() {
-		q.out.Set(tmp)
+	if !ok {
+		return c.pesimistic(r, q)
 	}
 
-	return err
+	return c.execute(r, q, cdcs)
 }
 
-func (c *baseConn) optimistic(
-	r *buff.Reader,
+func (c *baseConn) sendOptimistic(
 	q *gfQuery,
-	cdcs codecPair,
+	in codecs.Encoder,
+	ids idPair,
 ) error {
 	headers := copyHeaders(q.headers)
 
@@ -336,9 +426,9 @@ func (c *baseConn) optimistic(
 	w.PushUint8(q.fmt)
 	w.PushUint8(q.expCard)
 	w.PushString(q.cmd)
-	w.PushUUID(cdcs.in.DescriptorID())
-	w.PushUUID(cdcs.out.DescriptorID())
-	if e := cdcs.in.Encode(w, q.args, codecs.Path("args")); e != nil {
+	w.PushUUID(ids.in)
+	w.PushUUID(ids.out)
+	if e := in.Encode(w, q.args, codecs.Path("args")); e != nil {
 		return &invalidArgumentError{msg: e.Error()}
 	}
 	w.EndMessage()
@@ -350,16 +440,35 @@ func (c *baseConn) optimistic(
 		return &clientConnectionError{err: e}
 	}
 
+	return nil
+}
+
+// decodeData reads the results of an execute or optimistic execute
+// into q.out.
+func (c *baseConn) decodeData(
+	r *buff.Reader,
+	q *gfQuery,
+	decoder codecs.Decoder,
+) error {
 	tmp := q.out
 	err := error(nil)
 	if q.expCard == cardinality.One {
 		err = errZeroResults
 	}
+
+	var described, completed bool
 	done := buff.NewSignal()
 
 	for r.Next(done.Chan) {
 		switch r.MsgType {
 		case message.Data:
+			if decoder == nil {
+				// the results can not be decoded
+				// because the new descriptors were not usable.
+				r.DiscardMessage()
+				break
+			}
+
 			elmCount := r.PopUint16()
 			if elmCount != 1 {
 				panic(fmt.Sprintf(
@@ -372,13 +481,13 @@ func (c *baseConn) optimistic(
 
 			if !q.flat() {
 				val := reflect.New(q.outType).Elem()
-				cdcs.out.Decode(
+				decoder.Decode(
 					r.PopSlice(elmLen),
 					unsafe.Pointer(val.UnsafeAddr()),
 				)
 				tmp = reflect.Append(tmp, val)
 			} else {
-				cdcs.out.Decode(
+				decoder.Decode(
 					r.PopSlice(elmLen),
 					unsafe.Pointer(q.out.UnsafeAddr()),
 				)
@@ -388,8 +497,27 @@ func (c *baseConn) optimistic(
 				err = nil
 			}
 		case message.CommandComplete:
-			ignoreHeaders(r)
-			r.PopBytes() // command status
+			completed = true
+			c.decodeCommandComplete(r)
+		case message.CommandDataDescription:
+			// The server's type descriptors have changed.
+			// The descriptors are cached so that a retry uses them.
+			described = true
+			ids, descs, e := c.decodeCommandDataDescription(r, q)
+			if e == nil {
+				var cdcs codecPair
+				cdcs, e = c.buildCodecs(q, ids, descs)
+				decoder = cdcs.out
+			}
+
+			if e != nil {
+				if err == errZeroResults {
+					err = nil
+				}
+
+				decoder = nil
+				err = wrapAll(err, e)
+			}
 		case message.ReadyForCommand:
 			ignoreHeaders(r)
 			r.Discard(1) // transaction state
@@ -412,6 +540,12 @@ func (c *baseConn) optimistic(
 		return &clientConnectionError{err: r.Err}
 	}
 
+	// Protocol 0.x servers only describe optimistic executes
+	// that have stale type IDs.
+	if described && !completed && (err == nil || err == errZeroResults) {
+		return errNotExecuted
+	}
+
 	if !q.flat() {
 		q.out.Set(tmp)
 	}
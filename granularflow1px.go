@@ -0,0 +1,244 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedb
+
+import (
+	"errors"
+
+	"github.com/edgedb/edgedb-go/internal/buff"
+	"github.com/edgedb/edgedb-go/internal/cardinality"
+	"github.com/edgedb/edgedb-go/internal/codecs"
+	"github.com/edgedb/edgedb-go/internal/descriptor"
+	"github.com/edgedb/edgedb-go/internal/format"
+	"github.com/edgedb/edgedb-go/internal/header"
+	"github.com/edgedb/edgedb-go/internal/message"
+)
+
+// granularFlow1pX runs a query using the Parse and Execute messages
+// introduced in protocol 1.0.
+func (c *baseConn) granularFlow1pX(r *buff.Reader, q *gfQuery) error {
+	err := c.tryGranularFlow1pX(r, q)
+	if shouldRetryExecute(err) {
+		return c.tryGranularFlow1pX(r, q)
+	}
+
+	return err
+}
+
+func (c *baseConn) tryGranularFlow1pX(r *buff.Reader, q *gfQuery) error {
+	cdcs, ok, err := c.cachedCodecs(q)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		ids, descs, e := c.parse(r, q)
+		if e != nil {
+			return e
+		}
+
+		cdcs, err = c.buildCodecs(q, ids, descs)
+		if err != nil {
+			return err
+		}
+	}
+
+	if e := c.sendExecute1pX(q, cdcs.in, cdcs.out.DescriptorID()); e != nil {
+		return e
+	}
+
+	return c.decodeData(r, q, cdcs.out)
+}
+
+// shouldRetryExecute returns true if err was caused by the server's
+// type descriptors or state descriptor being different from the ones used
+// to encode the query. By the time the error is received
+// the new descriptors have been received as well.
+func shouldRetryExecute(err error) bool {
+	var edbErr Error
+	return errors.As(err, &edbErr) &&
+		(edbErr.Category(ParameterTypeMismatchError) ||
+			edbErr.Category(StateMismatchError))
+}
+
+// writeQueryFields writes the fields shared by Parse and Execute.
+func (c *baseConn) writeQueryFields(w *buff.Writer, q *gfQuery) error {
+	w.PushUint16(0) // no headers
+	w.PushUint64(header.AllowedCapabilities(q.headers))
+	w.PushUint64(header.CompilationFlags(q.headers))
+	w.PushUint64(header.ImplicitLimitOf(q.headers))
+	w.PushUint8(q.fmt)
+	w.PushUint8(q.expCard)
+	w.PushString(q.cmd)
+	return c.encodeState(w, q.state)
+}
+
+func (c *baseConn) parse(
+	r *buff.Reader,
+	q *gfQuery,
+) (idPair, descPair, error) {
+	w := buff.NewWriter(c.writeMemory[:0])
+	w.BeginMessage(message.Parse)
+	if e := c.writeQueryFields(w, q); e != nil {
+		return idPair{}, descPair{}, e
+	}
+	w.EndMessage()
+
+	w.BeginMessage(message.Sync)
+	w.EndMessage()
+
+	if e := w.Send(c.conn); e != nil {
+		return idPair{}, descPair{}, &clientConnectionError{err: e}
+	}
+
+	var (
+		err      error
+		ids      idPair
+		descs    descPair
+		received bool
+	)
+
+	done := buff.NewSignal()
+
+	for r.Next(done.Chan) {
+		switch r.MsgType {
+		case message.CommandDataDescription:
+			var e error
+			ids, descs, e = c.decodeCommandDataDescription(r, q)
+			err = wrapAll(err, e)
+			received = true
+		case message.ReadyForCommand:
+			ignoreHeaders(r)
+			r.Discard(1) // transaction state
+			done.Signal()
+		case message.ErrorResponse:
+			err = wrapAll(err, decodeError(r, q.cmd))
+		default:
+			if e := c.fallThrough(r); e != nil {
+				// the connection will not be usable after this x_x
+				return idPair{}, descPair{}, e
+			}
+		}
+	}
+
+	if r.Err != nil {
+		return idPair{}, descPair{}, &clientConnectionError{err: r.Err}
+	}
+
+	if err == nil && !received {
+		err = &binaryProtocolError{
+			msg: "the server did not send a CommandDataDescription",
+		}
+	}
+
+	return ids, descs, err
+}
+
+func (c *baseConn) sendExecute1pX(
+	q *gfQuery,
+	in codecs.Encoder,
+	outID UUID,
+) error {
+	w := buff.NewWriter(c.writeMemory[:0])
+	w.BeginMessage(message.Execute)
+	if e := c.writeQueryFields(w, q); e != nil {
+		return e
+	}
+	w.PushUUID(in.DescriptorID())
+	w.PushUUID(outID)
+	if e := in.Encode(w, q.args, codecs.Path("args")); e != nil {
+		return &invalidArgumentError{msg: e.Error()}
+	}
+	w.EndMessage()
+
+	w.BeginMessage(message.Sync)
+	w.EndMessage()
+
+	if e := w.Send(c.conn); e != nil {
+		return &clientConnectionError{err: e}
+	}
+
+	return nil
+}
+
+// scriptFlow1pX runs a script using the Execute message.
+// Scripts never take arguments or return data
+// so their type descriptors are known without parsing them first.
+func (c *baseConn) scriptFlow1pX(r *buff.Reader, q sfQuery) error {
+	gq := &gfQuery{
+		cmd:     q.cmd,
+		fmt:     format.Null,
+		expCard: cardinality.Many,
+		headers: q.headers,
+		state:   q.state,
+	}
+
+	in, err := codecs.BuildEncoder(descriptor.Descriptor{
+		Type: descriptor.Tuple,
+		ID:   descriptor.IDEmptyTuple,
+	})
+	if err != nil {
+		return &unsupportedFeatureError{msg: err.Error()}
+	}
+
+	err = c.tryScriptFlow1pX(r, gq, in)
+	if shouldRetryExecute(err) {
+		return c.tryScriptFlow1pX(r, gq, in)
+	}
+
+	return err
+}
+
+func (c *baseConn) tryScriptFlow1pX(
+	r *buff.Reader,
+	q *gfQuery,
+	in codecs.Encoder,
+) error {
+	if e := c.sendExecute1pX(q, in, descriptor.IDZero); e != nil {
+		return e
+	}
+
+	var err error
+	done := buff.NewSignal()
+
+	for r.Next(done.Chan) {
+		switch r.MsgType {
+		case message.CommandComplete:
+			c.decodeCommandComplete(r)
+		case message.CommandDataDescription:
+			_, _, e := c.decodeCommandDataDescription(r, q)
+			err = wrapAll(err, e)
+		case message.ReadyForCommand:
+			ignoreHeaders(r)
+			r.Discard(1) // transaction state
+			done.Signal()
+		case message.ErrorResponse:
+			err = wrapAll(err, decodeError(r, q.cmd))
+		default:
+			if e := c.fallThrough(r); e != nil {
+				// the connection will not be usable after this x_x
+				return e
+			}
+		}
+	}
+
+	if r.Err != nil {
+		return &clientConnectionError{err: r.Err}
+	}
+
+	return err
+}
@@ -0,0 +1,120 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedb
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/edgedb/edgedb-go/internal/buff"
+	"github.com/edgedb/edgedb-go/internal/cache"
+	"github.com/edgedb/edgedb-go/internal/cardinality"
+	"github.com/edgedb/edgedb-go/internal/codecs"
+	"github.com/edgedb/edgedb-go/internal/descriptor"
+	"github.com/edgedb/edgedb-go/internal/format"
+	"github.com/edgedb/edgedb-go/internal/message"
+	"github.com/edgedb/edgedb-go/internal/soc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeDataWithUnsupportedDescriptor(t *testing.T) {
+	int64ID := UUID{14: 0x01, 15: 0x05}
+	rangeID := UUID{0: 0x01}
+
+	w := buff.NewWriter(nil)
+
+	// the server describes the query with a range descriptor
+	// which is not supported.
+	w.BeginMessage(message.CommandDataDescription)
+	w.PushUint16(0) // no headers
+	w.PushUint8(cardinality.Many)
+	w.PushUUID(descriptor.IDZero)
+	w.PushUint32(0) // no input descriptor
+	w.PushUUID(rangeID)
+	w.BeginBytes()
+	w.PushUint8(0x02) // base scalar
+	w.PushUUID(int64ID)
+	w.PushUint8(0x09) // range
+	w.PushUUID(rangeID)
+	w.PushUint16(0) // element type
+	w.EndBytes()
+	w.EndMessage()
+
+	// the data has the new shape.
+	w.BeginMessage(message.Data)
+	w.PushUint16(1) // number of elements
+	w.PushUint32(3) // element length
+	w.PushUint8(1)
+	w.PushUint8(2)
+	w.PushUint8(3)
+	w.EndMessage()
+
+	w.BeginMessage(message.CommandComplete)
+	w.PushUint16(0) // no headers
+	w.PushString("SELECT")
+	w.EndMessage()
+
+	w.BeginMessage(message.ReadyForCommand)
+	w.PushUint16(0)  // no headers
+	w.PushUint8('I') // transaction state
+	w.EndMessage()
+
+	var buf bytes.Buffer
+	require.Nil(t, w.Send(&buf))
+
+	toBeDeserialized := make(chan *soc.Data, 1)
+	toBeDeserialized <- &soc.Data{Buf: buf.Bytes()}
+	r := buff.NewReader(toBeDeserialized)
+
+	c := &baseConn{
+		typeIDCache:     cache.New(1),
+		inCodecCache:    cache.New(1),
+		outCodecCache:   cache.New(1),
+		protocolVersion: version{0, 13},
+	}
+
+	var result []int64
+	q, err := newQuery(
+		"SELECT range(1, 3)",
+		format.Binary,
+		cardinality.Many,
+		nil,
+		msgHeaders{},
+		&result,
+	)
+	require.Nil(t, err)
+
+	decoder, err := codecs.BuildDecoder(
+		descriptor.Descriptor{Type: descriptor.BaseScalar, ID: int64ID},
+		q.outType,
+		codecs.Path("[]int64"),
+	)
+	require.Nil(t, err)
+
+	err = c.decodeData(r, q, decoder)
+	var edbErr Error
+	require.True(t, errors.As(err, &edbErr), err)
+	assert.True(t, edbErr.Category(UnexpectedMessageError), err)
+	assert.EqualError(
+		t,
+		err,
+		"edgedb.UnexpectedMessageError: unknown descriptor type 0x9",
+	)
+	assert.Nil(t, result)
+}
@@ -0,0 +1,134 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedb
+
+import (
+	"context"
+	"time"
+)
+
+// Ping checks that the server is reachable
+// by running a trivial query on one of the pool's connections.
+func (p *Pool) Ping(ctx context.Context) error {
+	conn, err := p.acquire(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = conn.ping(ctx)
+	return firstError(err, p.release(conn, err))
+}
+
+// ping runs a trivial query to check that the connection is usable.
+// Unlike other queries ping does not reconnect a closed connection.
+func (b *reconnectingConn) ping(ctx context.Context) error {
+	if e := b.assertUnborrowed(); e != nil {
+		return e
+	}
+
+	if b.conn == nil || b.conn.conn == nil || b.isClosed {
+		return &clientConnectionClosedError{msg: "connection closed"}
+	}
+
+	q := sfQuery{cmd: "SELECT 1;", headers: b.scriptHeaders()}
+	return b.conn.ScriptFlow(ctx, q)
+}
+
+func (p *Pool) healthCheckLoop(period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.healthCheckDone:
+			return
+		case <-ticker.C:
+			p.healthCheck(period)
+		}
+	}
+}
+
+// healthCheck pings the pool's idle connections,
+// closes the ones that are dead, expired or have been idle for too long
+// and then connects new connections until there are MinConns idle.
+func (p *Pool) healthCheck(timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	freeConns, _, _ := p.capacity.chans()
+	for n := len(freeConns); n > 0; n-- {
+		select {
+		case conn := <-freeConns:
+			p.checkConn(ctx, conn)
+		default:
+			// the connections were acquired in the mean time.
+			n = 0
+		}
+	}
+
+	p.refill(ctx)
+}
+
+// checkConn returns conn to the pool if it is healthy
+// and closes it otherwise.
+func (p *Pool) checkConn(ctx context.Context, conn *reconnectingConn) {
+	idle := p.maxConnIdleTime > 0 &&
+		time.Since(conn.idleSince) > p.maxConnIdleTime
+
+	if idle || p.expired(conn) {
+		_ = p.discard(conn)
+		return
+	}
+
+	if e := conn.ping(ctx); e != nil {
+		_ = p.discard(conn)
+		return
+	}
+
+	// idleSince is not changed so that pinging a connection
+	// does not prevent it from reaching MaxConnIdleTime.
+	_ = p.put(conn)
+}
+
+// refill connects new connections until there are MinConns idle
+// or there is no unconnected capacity left.
+func (p *Pool) refill(ctx context.Context) {
+	for {
+		minConns, _ := p.capacity.size()
+		if p.capacity.idle() >= minConns {
+			return
+		}
+
+		_, potentialConns, _ := p.capacity.chans()
+		select {
+		case <-potentialConns:
+		default:
+			return
+		}
+
+		conn, err := p.newConn(ctx)
+		if err != nil {
+			p.capacity.putToken()
+			return
+		}
+
+		conn.idleSince = time.Now()
+		if e := p.put(conn); e != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,121 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedb
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func waitForAccepted(t *testing.T, server *stallingServer, n int32) {
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&server.accepted) < n {
+		require.True(t, time.Now().Before(deadline), "timed out")
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestPoolPing(t *testing.T) {
+	server := startStallingServer(t)
+	defer server.close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p, err := Connect(ctx, server.options())
+	require.Nil(t, err)
+	defer p.Close() // nolint:errcheck
+
+	require.Nil(t, p.Ping(ctx))
+
+	server.dropConns()
+	assert.NotNil(t, p.Ping(ctx))
+
+	// the dead connection was replaced.
+	require.Nil(t, p.Ping(ctx))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&server.accepted))
+}
+
+func TestHealthCheckReplacesDeadConns(t *testing.T) {
+	server := startStallingServer(t)
+	defer server.close()
+
+	o := server.options()
+	o.HealthCheckPeriod = 10 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p, err := Connect(ctx, o)
+	require.Nil(t, err)
+	defer p.Close() // nolint:errcheck
+
+	server.dropConns()
+	waitForAccepted(t, server, 2)
+	require.Nil(t, p.Execute(ctx, "SELECT 1"))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&server.accepted))
+}
+
+func TestHealthCheckClosesIdleConns(t *testing.T) {
+	server := startStallingServer(t)
+	defer server.close()
+
+	o := server.options()
+	o.HealthCheckPeriod = 10 * time.Millisecond
+	o.MaxConnIdleTime = 15 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p, err := Connect(ctx, o)
+	require.Nil(t, err)
+	defer p.Close() // nolint:errcheck
+
+	// idle connections are replaced to keep MinConns open.
+	waitForAccepted(t, server, 3)
+	require.Nil(t, p.Execute(ctx, "SELECT 1"))
+}
+
+func TestPoolClosesExpiredConns(t *testing.T) {
+	server := startStallingServer(t)
+	defer server.close()
+
+	o := server.options()
+	o.MaxConnLifetime = 20 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p, err := Connect(ctx, o)
+	require.Nil(t, err)
+	defer p.Close() // nolint:errcheck
+
+	require.Nil(t, p.Execute(ctx, "SELECT 1"))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&server.accepted))
+
+	time.Sleep(30 * time.Millisecond)
+
+	// the expired connection is closed when it is released.
+	require.Nil(t, p.Execute(ctx, "SELECT 1"))
+	require.Nil(t, p.Execute(ctx, "SELECT 1"))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&server.accepted))
+}
@@ -0,0 +1,258 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedb
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// hostMinBackoff is how long a host is marked down
+	// after it fails for the first time.
+	hostMinBackoff = 500 * time.Millisecond
+
+	// hostMaxBackoff is the longest a host is marked down.
+	hostMaxBackoff = 30 * time.Second
+)
+
+// HostSelectionMode determines the order
+// in which hosts are tried when connecting.
+type HostSelectionMode string
+
+// The available modes are:
+const (
+	// HostSelectionOrdered tries hosts in the order they were specified.
+	HostSelectionOrdered HostSelectionMode = "ordered"
+
+	// HostSelectionRoundRobin starts each connection attempt
+	// with the host after the one the previous attempt started with.
+	HostSelectionRoundRobin HostSelectionMode = "round_robin"
+
+	// HostSelectionRandom tries hosts in a random order.
+	HostSelectionRandom HostSelectionMode = "random"
+
+	// HostSelectionCustom tries hosts in the order
+	// returned by Options.HostSelector.
+	HostSelectionCustom HostSelectionMode = "custom"
+)
+
+// HostSelector returns the order in which to try to connect to addresses.
+// Each address is either a host:port pair or the path of a Unix socket.
+// Addresses not included in the result are not tried.
+// It may be called concurrently from multiple goroutines.
+type HostSelector func(addresses []string) []string
+
+// hostState is the connection history of a host.
+type hostState struct {
+	// failures is the number of consecutive failed connection attempts.
+	failures int
+
+	// downUntil is when a host that failed may be tried again.
+	downUntil time.Time
+
+	// conns is the number of connections to the host.
+	conns int
+}
+
+// markDown records a failure.
+// The time the host is down doubles with each consecutive failure.
+func (s *hostState) markDown() {
+	backoff := hostMaxBackoff
+	if s.failures < 16 {
+		backoff = hostMinBackoff << s.failures
+		if backoff > hostMaxBackoff {
+			backoff = hostMaxBackoff
+		}
+	}
+
+	s.failures++
+	s.downUntil = time.Now().Add(backoff)
+}
+
+// markUp records a success.
+func (s *hostState) markUp() {
+	s.failures = 0
+	s.downUntil = time.Time{}
+}
+
+// isDown returns true if the host should not be tried yet.
+func (s *hostState) isDown() bool {
+	return time.Now().Before(s.downUntil)
+}
+
+// hostSet chooses which hosts to connect to.
+// It is shared by all connections in a pool.
+type hostSet struct {
+	mu       sync.Mutex
+	addrs    []*dialArgs
+	states   map[*dialArgs]*hostState
+	mode     HostSelectionMode
+	selector HostSelector
+
+	// next is the index of the first host to try in round robin mode.
+	next int
+}
+
+func newHostSet(addrs []*dialArgs, opts *Options) (*hostSet, error) {
+	mode := opts.HostSelection
+	switch mode {
+	case "":
+		mode = HostSelectionOrdered
+	case HostSelectionOrdered, HostSelectionRoundRobin, HostSelectionRandom:
+	case HostSelectionCustom:
+		if opts.HostSelector == nil {
+			return nil, &configurationError{
+				msg: "HostSelector is required for HostSelectionCustom",
+			}
+		}
+	default:
+		return nil, &configurationError{msg: fmt.Sprintf(
+			"invalid HostSelection: %q", mode,
+		)}
+	}
+
+	states := make(map[*dialArgs]*hostState, len(addrs))
+	for _, addr := range addrs {
+		states[addr] = &hostState{}
+	}
+
+	return &hostSet{
+		addrs:    addrs,
+		states:   states,
+		mode:     mode,
+		selector: opts.HostSelector,
+	}, nil
+}
+
+// order returns the hosts in the order they should be tried.
+// Hosts that are marked down are skipped while any host is up.
+// If every host is down they are all returned
+// in the order that they come back up
+// along with how long it is until the first one does.
+func (s *hostSet) order() ([]*dialArgs, time.Duration) {
+	addrs := s.selected()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	up := make([]*dialArgs, 0, len(addrs))
+	for _, addr := range addrs {
+		if !s.states[addr].isDown() {
+			up = append(up, addr)
+		}
+	}
+
+	if len(up) > 0 || len(addrs) == 0 {
+		return up, 0
+	}
+
+	sort.SliceStable(addrs, func(i, j int) bool {
+		a := s.states[addrs[i]].downUntil
+		b := s.states[addrs[j]].downUntil
+		return a.Before(b)
+	})
+
+	return addrs, time.Until(s.states[addrs[0]].downUntil)
+}
+
+// selected returns the hosts in the order chosen by the selection mode.
+func (s *hostSet) selected() []*dialArgs {
+	addrs := make([]*dialArgs, len(s.addrs))
+	copy(addrs, s.addrs)
+
+	switch s.mode {
+	case HostSelectionRoundRobin:
+		s.mu.Lock()
+		start := s.next
+		s.next = (s.next + 1) % len(addrs)
+		s.mu.Unlock()
+
+		return append(addrs[start:], addrs[:start]...)
+	case HostSelectionRandom:
+		rand.Shuffle(len(addrs), func(i, j int) {
+			addrs[i], addrs[j] = addrs[j], addrs[i]
+		})
+
+		return addrs
+	case HostSelectionCustom:
+		byAddress := make(map[string]*dialArgs, len(addrs))
+		addresses := make([]string, len(addrs))
+		for i, addr := range addrs {
+			byAddress[addr.address] = addr
+			addresses[i] = addr.address
+		}
+
+		var result []*dialArgs
+		for _, address := range s.selector(addresses) {
+			if addr, ok := byAddress[address]; ok {
+				result = append(result, addr)
+			}
+		}
+
+		return result
+	default:
+		return addrs
+	}
+}
+
+// failed marks addr down.
+func (s *hostSet) failed(addr *dialArgs) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.states[addr].markDown()
+}
+
+// connected records that a connection moved from the prev host to addr.
+// prev is nil for new connections.
+func (s *hostSet) connected(prev, addr *dialArgs) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if prev != nil {
+		s.states[prev].conns--
+	}
+
+	state := s.states[addr]
+	state.markUp()
+	state.conns++
+}
+
+// closed records that a connection to addr was closed.
+func (s *hostSet) closed(addr *dialArgs) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.states[addr].conns--
+}
+
+// conns returns the number of connections to each host address.
+func (s *hostSet) conns() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conns := make(map[string]int, len(s.addrs))
+	for _, addr := range s.addrs {
+		conns[addr.address] = s.states[addr].conns
+	}
+
+	return conns
+}
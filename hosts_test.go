@@ -0,0 +1,314 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedb
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testAddrs() []*dialArgs {
+	return []*dialArgs{
+		{"tcp", "a:5656"},
+		{"tcp", "b:5656"},
+		{"tcp", "c:5656"},
+	}
+}
+
+func order(hosts *hostSet) []*dialArgs {
+	addrs, _ := hosts.order()
+	return addrs
+}
+
+func addresses(addrs []*dialArgs) []string {
+	result := make([]string, len(addrs))
+	for i, addr := range addrs {
+		result[i] = addr.address
+	}
+
+	return result
+}
+
+func TestHostSelectionOrdered(t *testing.T) {
+	hosts, err := newHostSet(testAddrs(), &Options{})
+	require.Nil(t, err)
+
+	expected := []string{"a:5656", "b:5656", "c:5656"}
+	assert.Equal(t, expected, addresses(order(hosts)))
+	assert.Equal(t, expected, addresses(order(hosts)))
+}
+
+func TestHostSelectionRoundRobin(t *testing.T) {
+	hosts, err := newHostSet(
+		testAddrs(),
+		&Options{HostSelection: HostSelectionRoundRobin},
+	)
+	require.Nil(t, err)
+
+	assert.Equal(
+		t,
+		[]string{"a:5656", "b:5656", "c:5656"},
+		addresses(order(hosts)),
+	)
+	assert.Equal(
+		t,
+		[]string{"b:5656", "c:5656", "a:5656"},
+		addresses(order(hosts)),
+	)
+	assert.Equal(
+		t,
+		[]string{"c:5656", "a:5656", "b:5656"},
+		addresses(order(hosts)),
+	)
+	assert.Equal(
+		t,
+		[]string{"a:5656", "b:5656", "c:5656"},
+		addresses(order(hosts)),
+	)
+}
+
+func TestHostSelectionRandom(t *testing.T) {
+	hosts, err := newHostSet(
+		testAddrs(),
+		&Options{HostSelection: HostSelectionRandom},
+	)
+	require.Nil(t, err)
+
+	assert.ElementsMatch(
+		t,
+		[]string{"a:5656", "b:5656", "c:5656"},
+		addresses(order(hosts)),
+	)
+}
+
+func TestHostSelectionCustom(t *testing.T) {
+	hosts, err := newHostSet(
+		testAddrs(),
+		&Options{
+			HostSelection: HostSelectionCustom,
+			HostSelector: func(addresses []string) []string {
+				return []string{"c:5656", "unknown:5656", "a:5656"}
+			},
+		},
+	)
+	require.Nil(t, err)
+
+	assert.Equal(
+		t,
+		[]string{"c:5656", "a:5656"},
+		addresses(order(hosts)),
+	)
+}
+
+func TestHostSelectionInvalid(t *testing.T) {
+	_, err := newHostSet(
+		testAddrs(),
+		&Options{HostSelection: HostSelectionCustom},
+	)
+	assert.EqualError(
+		t,
+		err,
+		"edgedb.ConfigurationError: "+
+			"HostSelector is required for HostSelectionCustom",
+	)
+
+	_, err = newHostSet(testAddrs(), &Options{HostSelection: "fastest"})
+	assert.EqualError(
+		t,
+		err,
+		`edgedb.ConfigurationError: invalid HostSelection: "fastest"`,
+	)
+}
+
+func TestHostSelectionMarksFailedHostsDown(t *testing.T) {
+	addrs := testAddrs()
+	hosts, err := newHostSet(addrs, &Options{})
+	require.Nil(t, err)
+
+	hosts.failed(addrs[0])
+	hosts.failed(addrs[1])
+	hosts.failed(addrs[1])
+	assert.Equal(t, []string{"c:5656"}, addresses(order(hosts)))
+
+	// the backoff doubles with each failure.
+	a := hosts.states[addrs[0]]
+	b := hosts.states[addrs[1]]
+	assert.True(t, b.downUntil.Sub(a.downUntil) > hostMinBackoff/2)
+
+	// a host is tried in order again once its backoff has expired.
+	a.downUntil = time.Now().Add(-time.Millisecond)
+	assert.Equal(t, []string{"a:5656", "c:5656"}, addresses(order(hosts)))
+
+	hosts.connected(nil, addrs[1])
+	assert.Equal(t, 0, b.failures)
+	assert.Equal(
+		t,
+		[]string{"a:5656", "b:5656", "c:5656"},
+		addresses(order(hosts)),
+	)
+}
+
+func TestHostSelectionWaitsWhenAllHostsAreDown(t *testing.T) {
+	addrs := testAddrs()
+	hosts, err := newHostSet(addrs, &Options{})
+	require.Nil(t, err)
+
+	hosts.failed(addrs[0])
+	hosts.failed(addrs[0])
+	hosts.failed(addrs[1])
+	hosts.failed(addrs[2])
+	hosts.states[addrs[2]].downUntil = time.Now().Add(2 * time.Second)
+
+	down, wait := hosts.order()
+	assert.Equal(
+		t,
+		[]string{"b:5656", "a:5656", "c:5656"},
+		addresses(down),
+	)
+	assert.True(t, wait > 0, wait)
+	assert.True(t, wait <= hostMinBackoff, wait)
+}
+
+func TestHostSelectionBackoffIsCapped(t *testing.T) {
+	addrs := testAddrs()
+	hosts, err := newHostSet(addrs, &Options{})
+	require.Nil(t, err)
+
+	for i := 0; i < 100; i++ {
+		hosts.failed(addrs[0])
+	}
+
+	downFor := time.Until(hosts.states[addrs[0]].downUntil)
+	assert.True(t, downFor <= hostMaxBackoff, downFor)
+	assert.True(t, downFor > hostMaxBackoff/2, downFor)
+}
+
+func TestPoolFailsOverToNextHost(t *testing.T) {
+	down, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	downAddr := down.Addr().(*net.TCPAddr)
+	require.Nil(t, down.Close())
+
+	server := startStallingServer(t)
+	defer server.close()
+
+	o := server.options()
+	upAddr := server.ln.Addr().(*net.TCPAddr)
+	o.Hosts = []string{downAddr.IP.String(), upAddr.IP.String()}
+	o.Ports = []int{downAddr.Port, upAddr.Port}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p, err := Connect(ctx, o)
+	require.Nil(t, err)
+	defer p.Close() // nolint:errcheck
+
+	assert.Equal(
+		t,
+		map[string]int{downAddr.String(): 0, upAddr.String(): 1},
+		p.Stat().HostConns,
+	)
+
+	downHost := p.hosts.addrs[0]
+	assert.Equal(t, 1, p.hosts.states[downHost].failures)
+	assert.Equal(t, upAddr.String(), order(p.hosts)[0].address)
+}
+
+func TestPoolFailsOverAfterConnectionError(t *testing.T) {
+	// The first host closes connections before the handshake
+	// which is an error that is not retried on the same host.
+	closing, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer closing.Close() // nolint:errcheck
+
+	go func() {
+		for {
+			conn, e := closing.Accept()
+			if e != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+
+	server := startStallingServer(t)
+	defer server.close()
+
+	o := server.options()
+	closingAddr := closing.Addr().(*net.TCPAddr)
+	upAddr := server.ln.Addr().(*net.TCPAddr)
+	o.Hosts = []string{closingAddr.IP.String(), upAddr.IP.String()}
+	o.Ports = []int{closingAddr.Port, upAddr.Port}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p, err := Connect(ctx, o)
+	require.Nil(t, err)
+	defer p.Close() // nolint:errcheck
+
+	assert.Equal(
+		t,
+		map[string]int{closingAddr.String(): 0, upAddr.String(): 1},
+		p.Stat().HostConns,
+	)
+
+	closingHost := p.hosts.addrs[0]
+	assert.Equal(t, 1, p.hosts.states[closingHost].failures)
+}
+
+func TestPoolRoundRobinHosts(t *testing.T) {
+	server1 := startStallingServer(t)
+	defer server1.close()
+	server2 := startStallingServer(t)
+	defer server2.close()
+
+	addr1 := server1.ln.Addr().(*net.TCPAddr)
+	addr2 := server2.ln.Addr().(*net.TCPAddr)
+
+	o := server1.options()
+	o.Hosts = []string{addr1.IP.String(), addr2.IP.String()}
+	o.Ports = []int{addr1.Port, addr2.Port}
+	o.HostSelection = HostSelectionRoundRobin
+	o.MinConns = 2
+	o.MaxConns = 2
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p, err := Connect(ctx, o)
+	require.Nil(t, err)
+
+	assert.Equal(
+		t,
+		map[string]int{addr1.String(): 1, addr2.String(): 1},
+		p.Stat().HostConns,
+	)
+
+	require.Nil(t, p.Close())
+	assert.Equal(
+		t,
+		map[string]int{addr1.String(): 0, addr2.String(): 0},
+		p.Stat().HostConns,
+	)
+}
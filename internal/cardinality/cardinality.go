@@ -21,6 +21,11 @@ const (
 	NoResult = 0x6e
 	One      = 0x6f
 	Many     = 0x6d
+
+	// ExactlyOne and AtLeastOne are only reported
+	// by protocol 1.0 and later.
+	ExactlyOne = 0x41
+	AtLeastOne = 0x4d
 )
 
 // ToStr maps cardinality values to their string representation.
@@ -28,4 +33,7 @@ var ToStr = map[uint8]string{
 	NoResult: "NO_RESULT",
 	One:      "ONE",
 	Many:     "MANY",
+
+	ExactlyOne: "EXACTLY_ONE",
+	AtLeastOne: "AT_LEAST_ONE",
 }
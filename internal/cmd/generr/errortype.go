@@ -32,12 +32,7 @@ func (t *errorType) privateName() string {
 func parseType(typ []interface{}, lookup map[string]string) *errorType {
 	name := typ[0].(string)
 	errType := &errorType{
-		code: [4]uint8{
-			uint8(typ[2].(float64)),
-			uint8(typ[3].(float64)),
-			uint8(typ[4].(float64)),
-			uint8(typ[5].(float64)),
-		},
+		code: parseCode(typ),
 		name: name,
 	}
 
@@ -54,6 +49,67 @@ func parseType(typ []interface{}, lookup map[string]string) *errorType {
 	return errType
 }
 
+func parseCode(typ []interface{}) [4]uint8 {
+	return [4]uint8{
+		uint8(typ[2].(float64)),
+		uint8(typ[3].(float64)),
+		uint8(typ[4].(float64)),
+		uint8(typ[5].(float64)),
+	}
+}
+
+// protocol1Types are error types used by protocol 1.0
+// that are not reported by older servers.
+var protocol1Types = [][]interface{}{
+	{
+		"ParameterTypeMismatchError", "InputDataError",
+		3.0, 2.0, 1.0, 0.0,
+		[]interface{}{},
+	},
+	{
+		"StateMismatchError", "InputDataError",
+		3.0, 2.0, 2.0, 0.0,
+		[]interface{}{"SHOULD_RETRY"},
+	},
+}
+
+// addProtocol1Types adds the protocol1Types that are missing from data
+// before the first type with a greater code.
+func addProtocol1Types(data [][]interface{}) [][]interface{} {
+	names := make(map[string]bool, len(data))
+	for _, t := range data {
+		names[t[0].(string)] = true
+	}
+
+	for _, typ := range protocol1Types {
+		if names[typ[0].(string)] {
+			continue
+		}
+
+		code := parseCode(typ)
+		i := 0
+		for i < len(data) && !codeLess(code, parseCode(data[i])) {
+			i++
+		}
+
+		data = append(data, nil)
+		copy(data[i+1:], data[i:])
+		data[i] = typ
+	}
+
+	return data
+}
+
+func codeLess(a, b [4]uint8) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+
+	return false
+}
+
 func parseTypes(data [][]interface{}) []*errorType {
 	lookup := make(map[string]string, len(data))
 	for _, t := range data {
@@ -147,6 +147,7 @@ func main() {
 		log.Fatal(e)
 	}
 
+	data = addProtocol1Types(data)
 	types := parseTypes(data)
 	tags := parseTags(data)
 
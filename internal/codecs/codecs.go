@@ -17,7 +17,6 @@
 package codecs
 
 import (
-	"errors"
 	"fmt"
 	"reflect"
 	"unsafe"
@@ -67,7 +66,9 @@ func BuildEncoder(desc descriptor.Descriptor) (Encoder, error) {
 	case descriptor.Set:
 		return nil, fmt.Errorf("sets can not be encoded")
 	case descriptor.Object:
-		return nil, fmt.Errorf("objects can not be encoded")
+		return buildArgEncoder(desc)
+	case descriptor.InputShape:
+		return buildInputShapeEncoder(desc)
 	case descriptor.BaseScalar, descriptor.Enum:
 		return buildScalarEncoder(desc)
 	case descriptor.Tuple:
@@ -82,11 +83,12 @@ func BuildEncoder(desc descriptor.Descriptor) (Encoder, error) {
 }
 
 func buildScalarEncoder(desc descriptor.Descriptor) (Encoder, error) {
-	if desc.ID == decimalID {
-		return &decimalEncoder{}, nil
+	codec, err := buildScalarCodec(desc)
+	if err != nil {
+		return nil, err
 	}
 
-	return buildScalarCodec(desc)
+	return &optionalEncoder{codec}, nil
 }
 
 // BuildDecoder builds a Decoder from a Descriptor.
@@ -99,6 +101,14 @@ func BuildDecoder(
 		return noOpDecoder{}, nil
 	}
 
+	if typ.Kind() == reflect.Ptr && typ != bigIntType {
+		return buildPointerDecoder(desc, typ, path)
+	}
+
+	if optionalTypes[typ] {
+		return buildOptionalDecoder(desc, typ, path)
+	}
+
 	switch desc.Type {
 	case descriptor.Set:
 		return buildSetDecoder(desc, typ, path)
@@ -164,9 +174,7 @@ func buildScalarCodec(desc descriptor.Descriptor) (Codec, error) {
 	case float64ID:
 		return &float64Codec{}, nil
 	case decimalID:
-		return nil, errors.New("decimal codec not implemented. " +
-			"Consider implementing your own edgedb.DecimalMarshaler " +
-			"and edgedb.DecimalUnmarshaler.")
+		return &decimalCodec{}, nil
 	case boolID:
 		return &boolCodec{}, nil
 	case dateTimeID:
@@ -0,0 +1,104 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codecs
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/edgedb/edgedb-go/internal/buff"
+	"github.com/edgedb/edgedb-go/internal/descriptor"
+	types "github.com/edgedb/edgedb-go/internal/edgedbtypes"
+)
+
+func buildInputShapeEncoder(desc descriptor.Descriptor) (Encoder, error) {
+	fields := make([]*EncoderField, len(desc.Fields))
+
+	for i, field := range desc.Fields {
+		encoder, err := BuildEncoder(field.Desc)
+		if err != nil {
+			return nil, err
+		}
+
+		fields[i] = &EncoderField{
+			name:    field.Name,
+			encoder: encoder,
+		}
+	}
+
+	return &inputShapeEncoder{desc.ID, fields}, nil
+}
+
+// inputShapeEncoder encodes a map[string]interface{}
+// as a sparse object. Only the fields present in the map are sent.
+type inputShapeEncoder struct {
+	id     types.UUID
+	fields []*EncoderField
+}
+
+func (c *inputShapeEncoder) DescriptorID() types.UUID { return c.id }
+
+func (c *inputShapeEncoder) Encode(
+	w *buff.Writer,
+	val interface{},
+	path Path,
+) error {
+	in, ok := val.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf(
+			"expected %v to be map[string]interface{} got %T", path, val,
+		)
+	}
+
+	known := make(map[string]bool, len(c.fields))
+	for _, field := range c.fields {
+		known[field.name] = true
+	}
+
+	unknown := []string{}
+	for name := range in {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf(
+			"found unknown field %v", path.AddField(unknown[0]),
+		)
+	}
+
+	w.BeginBytes()
+	w.PushUint32(uint32(len(in)))
+
+	for i, field := range c.fields {
+		v, ok := in[field.name]
+		if !ok {
+			continue
+		}
+
+		w.PushUint32(uint32(i))
+		e := field.encoder.Encode(w, v, path.AddField(field.name))
+		if e != nil {
+			return e
+		}
+	}
+
+	w.EndBytes()
+	return nil
+}
@@ -0,0 +1,103 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codecs
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/edgedb/edgedb-go/internal/buff"
+	"github.com/edgedb/edgedb-go/internal/descriptor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func inputShapeFixture(t *testing.T) Encoder {
+	encoder, err := BuildEncoder(descriptor.Descriptor{
+		Type: descriptor.InputShape,
+		Fields: []*descriptor.Field{
+			{
+				Name: "module",
+				Desc: descriptor.Descriptor{
+					Type: descriptor.BaseScalar,
+					ID:   strID,
+				},
+			},
+			{
+				Name: "limit",
+				Desc: descriptor.Descriptor{
+					Type: descriptor.BaseScalar,
+					ID:   int64ID,
+				},
+			},
+		},
+	})
+	require.Nil(t, err)
+
+	return encoder
+}
+
+func TestEncodeInputShapeOnlySendsPresentFields(t *testing.T) {
+	encoder := inputShapeFixture(t)
+
+	w := buff.NewWriter(nil)
+	w.BeginMessage(0)
+	err := encoder.Encode(
+		w,
+		map[string]interface{}{"limit": int64(7)},
+		Path("state"),
+	)
+	require.Nil(t, err)
+	w.EndMessage()
+
+	var buf bytes.Buffer
+	require.Nil(t, w.Send(&buf))
+
+	expected := []byte{
+		0, 0, 0, 0x1c, // message length
+		0, 0, 0, 0x14, // data length
+		0, 0, 0, 1, // element count
+		0, 0, 0, 1, // field index
+		0, 0, 0, 8, // value length
+		0, 0, 0, 0, 0, 0, 0, 7, // value
+	}
+	assert.Equal(t, expected, buf.Bytes()[1:])
+}
+
+func TestEncodeInputShapeUnknownField(t *testing.T) {
+	encoder := inputShapeFixture(t)
+
+	w := buff.NewWriter(nil)
+	err := encoder.Encode(
+		w,
+		map[string]interface{}{"module": "default", "other": true},
+		Path("state"),
+	)
+	assert.EqualError(t, err, "found unknown field state.other")
+}
+
+func TestEncodeInputShapeWrongType(t *testing.T) {
+	encoder := inputShapeFixture(t)
+
+	w := buff.NewWriter(nil)
+	err := encoder.Encode(w, []string{"module"}, Path("state"))
+	assert.EqualError(
+		t,
+		err,
+		"expected state to be map[string]interface{} got []string",
+	)
+}
@@ -62,33 +62,25 @@ func (c *namedTupleEncoder) Encode(
 		return fmt.Errorf("expected %v to be []interface{} got %T", path, val)
 	}
 
-	elmCount := len(c.fields)
-
-	w.BeginBytes()
-	w.PushUint32(uint32(elmCount))
-
 	if len(args) != 1 {
 		return fmt.Errorf(
 			"wrong number of arguments, expected 1 got: %v", len(args),
 		)
 	}
 
-	in, ok := args[0].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf(
-			"expected %v to be map[string]interface{} got %T", path, args[0],
-		)
+	in, err := c.namedArgs(args[0], path)
+	if err != nil {
+		return err
 	}
 
-	var err error
-	for _, field := range c.fields {
-		w.PushUint32(0) // reserved
-		err = field.encoder.Encode(
-			w,
-			in[field.name],
-			path.AddField(field.name),
-		)
+	elmCount := len(c.fields)
 
+	w.BeginBytes()
+	w.PushUint32(uint32(elmCount))
+
+	for i, field := range c.fields {
+		w.PushUint32(0) // reserved
+		err = field.encoder.Encode(w, in[i], path.AddField(field.name))
 		if err != nil {
 			return err
 		}
@@ -98,6 +90,181 @@ func (c *namedTupleEncoder) Encode(
 	return nil
 }
 
+// namedArgs returns the value for each field from val.
+// val is a map with string keys or a struct (or a pointer to a struct)
+// with fields matched by their edgedb tag or name.
+func (c *namedTupleEncoder) namedArgs(
+	val interface{},
+	path Path,
+) ([]interface{}, error) {
+	v := reflect.ValueOf(val)
+	if v.Kind() == reflect.Ptr && v.Type().Elem().Kind() == reflect.Struct {
+		if v.IsNil() {
+			return nil, fmt.Errorf("expected %v not to be nil", path)
+		}
+
+		v = v.Elem()
+	}
+
+	switch {
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		return c.mapArgs(v, path)
+	case v.Kind() == reflect.Struct:
+		return c.structArgs(v, path)
+	default:
+		return nil, fmt.Errorf(
+			"expected %v to be a map with string keys or a struct got %T",
+			path, val,
+		)
+	}
+}
+
+func (c *namedTupleEncoder) mapArgs(
+	v reflect.Value,
+	path Path,
+) ([]interface{}, error) {
+	in, ok := v.Interface().(map[string]interface{})
+	if !ok {
+		in = make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			in[iter.Key().String()] = iter.Value().Interface()
+		}
+	}
+
+	args := make([]interface{}, len(c.fields))
+	for i, field := range c.fields {
+		arg, ok := in[field.name]
+		if !ok {
+			return nil, fmt.Errorf(
+				"missing argument %v", path.AddField(field.name),
+			)
+		}
+
+		args[i] = arg
+	}
+
+	if len(in) > len(c.fields) {
+		for name := range in {
+			if !c.hasField(name) {
+				return nil, fmt.Errorf(
+					"found unknown argument %v", path.AddField(name),
+				)
+			}
+		}
+	}
+
+	return args, nil
+}
+
+func (c *namedTupleEncoder) structArgs(
+	v reflect.Value,
+	path Path,
+) ([]interface{}, error) {
+	typ := v.Type()
+	fields := argFields(typ, nil)
+	used := make(map[string]bool, len(c.fields))
+	args := make([]interface{}, len(c.fields))
+
+	for i, field := range c.fields {
+		sf, ok := argField(fields, field.name)
+		if !ok {
+			return nil, fmt.Errorf(
+				"missing argument %v: expected %v to have "+
+					"an exported field with the tag `edgedb:\"%v\"`",
+				path.AddField(field.name), typ, field.name,
+			)
+		}
+
+		used[fmt.Sprint(sf.Index)] = true
+		args[i] = v.FieldByIndex(sf.Index).Interface()
+	}
+
+	for _, sf := range fields {
+		if used[fmt.Sprint(sf.Index)] {
+			continue
+		}
+
+		return nil, fmt.Errorf(
+			"found unknown argument %v", path.AddField(argName(sf)),
+		)
+	}
+
+	return args, nil
+}
+
+// argFields returns the exported fields of typ
+// including the fields promoted from embedded structs.
+// Shallower fields come before deeper ones.
+// The returned fields have their full index path.
+func argFields(typ reflect.Type, index []int) []reflect.StructField {
+	var (
+		fields   []reflect.StructField
+		embedded []reflect.StructField
+	)
+
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		sf.Index = append(append([]int{}, index...), i)
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			embedded = append(embedded, sf)
+			continue
+		}
+
+		fields = append(fields, sf)
+	}
+
+	for _, sf := range embedded {
+		fields = append(fields, argFields(sf.Type, sf.Index)...)
+	}
+
+	return fields
+}
+
+// argField finds the field whose tag or name matches name.
+// Tags take precedence over names.
+func argField(
+	fields []reflect.StructField,
+	name string,
+) (reflect.StructField, bool) {
+	for _, sf := range fields {
+		if sf.Tag.Get("edgedb") == name {
+			return sf, true
+		}
+	}
+
+	for _, sf := range fields {
+		if sf.Name == name {
+			return sf, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+// argName returns the argument name of a struct field.
+func argName(sf reflect.StructField) string {
+	if name := sf.Tag.Get("edgedb"); name != "" {
+		return name
+	}
+
+	return sf.Name
+}
+
+func (c *namedTupleEncoder) hasField(name string) bool {
+	for _, field := range c.fields {
+		if field.name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
 func buildNamedTupleDecoder(
 	desc descriptor.Descriptor,
 	typ reflect.Type,
@@ -160,6 +327,7 @@ func (c *namedTupleDecoder) Decode(r *buff.Reader, out unsafe.Pointer) {
 
 		elmLen := r.PopUint32()
 		if elmLen == 0xffffffff {
+			decodeMissing(field.decoder, pAdd(out, field.offset))
 			continue
 		}
 
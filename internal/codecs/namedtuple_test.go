@@ -0,0 +1,216 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codecs
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/edgedb/edgedb-go/internal/buff"
+	"github.com/edgedb/edgedb-go/internal/descriptor"
+	types "github.com/edgedb/edgedb-go/internal/edgedbtypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func namedArgsFixture(t *testing.T) Encoder {
+	encoder, err := BuildEncoder(descriptor.Descriptor{
+		Type: descriptor.NamedTuple,
+		ID:   types.UUID{6},
+		Fields: []*descriptor.Field{
+			scalarField("name", strID),
+			scalarField("limit", int64ID),
+		},
+	})
+	require.Nil(t, err)
+
+	return encoder
+}
+
+func encodeNamedArgs(t *testing.T, arg interface{}) ([]byte, error) {
+	w := buff.NewWriter(nil)
+	w.BeginMessage(0)
+	err := namedArgsFixture(t).Encode(w, []interface{}{arg}, Path("args"))
+	if err != nil {
+		return nil, err
+	}
+	w.EndMessage()
+
+	var buf bytes.Buffer
+	require.Nil(t, w.Send(&buf))
+	return buf.Bytes()[5:], nil
+}
+
+var namedArgsData = []byte{
+	0, 0, 0, 0x1f, // data length
+	0, 0, 0, 2, // element count
+	0, 0, 0, 0, // reserved
+	0, 0, 0, 3, // element length
+	'b', 'o', 'b',
+	0, 0, 0, 0, // reserved
+	0, 0, 0, 8, // element length
+	0, 0, 0, 0, 0, 0, 0, 7,
+}
+
+func TestEncodeNamedArgs(t *testing.T) {
+	type Params struct {
+		Name  string `edgedb:"name"`
+		Limit int64  `edgedb:"limit"`
+	}
+
+	type Named struct {
+		Name string `edgedb:"name"`
+	}
+
+	type Embedded struct {
+		Named
+		Limit int64 `edgedb:"limit"`
+	}
+
+	samples := []struct {
+		name string
+		arg  interface{}
+	}{
+		{"map", map[string]interface{}{"name": "bob", "limit": int64(7)}},
+		{"struct", Params{Name: "bob", Limit: 7}},
+		{"struct pointer", &Params{Name: "bob", Limit: 7}},
+		{"embedded struct", Embedded{Named{Name: "bob"}, 7}},
+	}
+
+	for _, s := range samples {
+		t.Run(s.name, func(t *testing.T) {
+			data, err := encodeNamedArgs(t, s.arg)
+			require.Nil(t, err)
+			assert.Equal(t, namedArgsData, data)
+		})
+	}
+}
+
+func TestEncodeNamedArgsTypedMap(t *testing.T) {
+	encoder, err := BuildEncoder(descriptor.Descriptor{
+		Type: descriptor.NamedTuple,
+		ID:   types.UUID{7},
+		Fields: []*descriptor.Field{
+			scalarField("a", strID),
+			scalarField("b", strID),
+		},
+	})
+	require.Nil(t, err)
+
+	w := buff.NewWriter(nil)
+	w.BeginMessage(0)
+	err = encoder.Encode(
+		w,
+		[]interface{}{map[string]string{"a": "x", "b": "y"}},
+		Path("args"),
+	)
+	require.Nil(t, err)
+	w.EndMessage()
+
+	var buf bytes.Buffer
+	require.Nil(t, w.Send(&buf))
+
+	expected := []byte{
+		0, 0, 0, 0x16, // data length
+		0, 0, 0, 2, // element count
+		0, 0, 0, 0, // reserved
+		0, 0, 0, 1, // element length
+		'x',
+		0, 0, 0, 0, // reserved
+		0, 0, 0, 1, // element length
+		'y',
+	}
+	assert.Equal(t, expected, buf.Bytes()[5:])
+}
+
+func TestEncodeNamedArgsErrors(t *testing.T) {
+	type Missing struct {
+		Name string `edgedb:"name"`
+	}
+
+	type Extra struct {
+		Name  string `edgedb:"name"`
+		Limit int64  `edgedb:"limit"`
+		Other bool   `edgedb:"other"`
+	}
+
+	type Unexported struct {
+		Name  string `edgedb:"name"`
+		limit int64
+	}
+
+	type Embedded struct {
+		Extra
+	}
+
+	samples := []struct {
+		name string
+		arg  interface{}
+		err  string
+	}{
+		{
+			"missing map key",
+			map[string]interface{}{"name": "bob"},
+			"missing argument args.limit",
+		},
+		{
+			"extra map key",
+			map[string]int64{"name": 1, "limit": 2, "other": 3},
+			"found unknown argument args.other",
+		},
+		{
+			"missing struct field",
+			Missing{},
+			"missing argument args.limit: expected codecs.Missing " +
+				"to have an exported field with the tag `edgedb:\"limit\"`",
+		},
+		{
+			"extra struct field",
+			Extra{},
+			"found unknown argument args.other",
+		},
+		{
+			"unexported struct field",
+			Unexported{limit: 1},
+			"missing argument args.limit: expected codecs.Unexported " +
+				"to have an exported field with the tag `edgedb:\"limit\"`",
+		},
+		{
+			"extra embedded struct field",
+			Embedded{},
+			"found unknown argument args.other",
+		},
+		{
+			"nil struct pointer",
+			(*Missing)(nil),
+			"expected args not to be nil",
+		},
+		{
+			"wrong type",
+			[]string{"bob"},
+			"expected args to be a map with string keys " +
+				"or a struct got []string",
+		},
+	}
+
+	for _, s := range samples {
+		t.Run(s.name, func(t *testing.T) {
+			_, err := encodeNamedArgs(t, s.arg)
+			assert.EqualError(t, err, s.err)
+		})
+	}
+}
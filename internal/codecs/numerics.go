@@ -18,8 +18,11 @@ package codecs
 
 import (
 	"fmt"
+	"math"
 	"math/big"
 	"reflect"
+	"strconv"
+	"strings"
 	"unsafe"
 
 	"github.com/edgedb/edgedb-go/internal/buff"
@@ -30,7 +33,8 @@ var (
 	decimalID = types.UUID{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 8}
 	bigIntID  = types.UUID{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0x10}
 
-	bigIntType = reflect.TypeOf(&big.Int{})
+	bigIntType  = reflect.TypeOf(&big.Int{})
+	decimalType = reflect.TypeOf(types.Decimal{})
 
 	big10k  = big.NewInt(10_000)
 	bigOne  = big.NewInt(1)
@@ -169,16 +173,58 @@ type DecimalUnmarshaler interface {
 	UnmarshalEdgeDBDecimal(data []byte) error
 }
 
-type decimalEncoder struct{}
+// decimalLayout is the memory layout for edgedbtypes.Decimal
+type decimalLayout struct {
+	digits string
+	scale  int32
+	neg    bool
+}
+
+type decimalCodec struct{}
+
+func (c *decimalCodec) Type() reflect.Type { return decimalType }
+
+func (c *decimalCodec) DescriptorID() types.UUID { return decimalID }
+
+func (c *decimalCodec) Decode(r *buff.Reader, out unsafe.Pointer) {
+	n := int(r.PopUint16())
+	weight := int(int16(r.PopUint16()))
+	sign := r.PopUint16()
+	scale := int(r.PopUint16())
+
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "%04d", r.PopUint16())
+	}
 
-func (c *decimalEncoder) DescriptorID() types.UUID { return decimalID }
+	// the base 10,000 digits are digits * 10,000^(weight-n+1)
+	// and are shifted to digits * 10^-scale.
+	digits := b.String()
+	shift := 4*(weight-n+1) + scale
+	if shift >= 0 {
+		digits += strings.Repeat("0", shift)
+	} else if -shift < len(digits) {
+		digits = digits[:len(digits)+shift]
+	} else {
+		digits = ""
+	}
+
+	digits = strings.TrimLeft(digits, "0")
 
-func (c *decimalEncoder) Encode(
+	d := (*decimalLayout)(out)
+	d.digits = digits
+	d.scale = int32(scale)
+	d.neg = sign == 0x4000 && digits != ""
+}
+
+func (c *decimalCodec) Encode(
 	w *buff.Writer,
 	val interface{},
 	path Path,
 ) error {
 	switch in := val.(type) {
+	case types.Decimal:
+		return encodeDecimal(w, (*decimalLayout)(unsafe.Pointer(&in)), path)
 	case DecimalMarshaler:
 		data, err := in.MarshalEdgeDBDecimal()
 		if err != nil {
@@ -189,7 +235,71 @@ func (c *decimalEncoder) Encode(
 		w.PushBytes(data)
 		w.EndBytes()
 	default:
-		return fmt.Errorf("expected %v to be *big.Int got %T", path, val)
+		return fmt.Errorf(
+			"expected %v to be edgedb.Decimal got %T", path, val,
+		)
+	}
+
+	return nil
+}
+
+func encodeDecimal(w *buff.Writer, d *decimalLayout, path Path) error {
+	if d.scale > math.MaxUint16 {
+		return fmt.Errorf("%v has too many digits after the decimal point "+
+			"to be encoded as decimal", path)
+	}
+
+	// pad the integer and fractional digits to whole base 10,000 digits.
+	digits := d.digits
+	whole := len(digits) - int(d.scale)
+	if whole < 0 {
+		digits = strings.Repeat("0", -whole) + digits
+		whole = 0
+	}
+
+	if pad := (4 - whole%4) % 4; pad > 0 {
+		digits = strings.Repeat("0", pad) + digits
+		whole += pad
+	}
+
+	if pad := (4 - len(digits)%4) % 4; pad > 0 {
+		digits += strings.Repeat("0", pad)
+	}
+
+	weight := whole/4 - 1
+	for strings.HasPrefix(digits, "0000") {
+		digits = digits[4:]
+		weight--
+	}
+
+	for strings.HasSuffix(digits, "0000") {
+		digits = digits[:len(digits)-4]
+	}
+
+	n := len(digits) / 4
+	if n == 0 {
+		weight = 0
+	}
+
+	if n > math.MaxUint16 ||
+		weight > math.MaxInt16 || weight < math.MinInt16 {
+		return fmt.Errorf("%v is out of range for decimal", path)
+	}
+
+	var sign uint16 = 0
+	if d.neg {
+		sign = 0x4000
+	}
+
+	w.PushUint32(uint32(8 + 2*n)) // data length
+	w.PushUint16(uint16(n))
+	w.PushUint16(uint16(int16(weight)))
+	w.PushUint16(sign)
+	w.PushUint16(uint16(d.scale))
+
+	for i := 0; i < len(digits); i += 4 {
+		digit, _ := strconv.ParseUint(digits[i:i+4], 10, 16)
+		w.PushUint16(uint16(digit))
 	}
 
 	return nil
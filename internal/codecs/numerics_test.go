@@ -0,0 +1,134 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codecs
+
+import (
+	"bytes"
+	"testing"
+	"unsafe"
+
+	"github.com/edgedb/edgedb-go/internal/buff"
+	types "github.com/edgedb/edgedb-go/internal/edgedbtypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodeDecimalData(t *testing.T, val interface{}) []byte {
+	w := buff.NewWriter(nil)
+	w.BeginMessage(0)
+	require.Nil(t, (&decimalCodec{}).Encode(w, val, Path("args[0]")))
+	w.EndMessage()
+
+	var buf bytes.Buffer
+	require.Nil(t, w.Send(&buf))
+
+	// skip the message type, message length and data length
+	return buf.Bytes()[9:]
+}
+
+func TestDecimalCodec(t *testing.T) {
+	samples := []struct {
+		decimal string
+		data    []byte
+	}{
+		{"-15000.6250000", []byte{
+			0x00, 0x03, // ndigits
+			0x00, 0x01, // weight
+			0x40, 0x00, // sign
+			0x00, 0x07, // dscale
+			0x00, 0x01, 0x13, 0x88, 0x18, 0x6a, // digits
+		}},
+		{"0", []byte{0, 0, 0, 0, 0, 0, 0, 0}},
+		{"0.00", []byte{0, 0, 0, 0, 0, 0, 0, 2}},
+		{"12.5", []byte{
+			0x00, 0x02, // ndigits
+			0x00, 0x00, // weight
+			0x00, 0x00, // sign
+			0x00, 0x01, // dscale
+			0x00, 0x0c, 0x13, 0x88, // digits
+		}},
+		{"0.0001", []byte{
+			0x00, 0x01, // ndigits
+			0xff, 0xff, // weight
+			0x00, 0x00, // sign
+			0x00, 0x04, // dscale
+			0x00, 0x01, // digits
+		}},
+		{"100000000", []byte{
+			0x00, 0x01, // ndigits
+			0x00, 0x02, // weight
+			0x00, 0x00, // sign
+			0x00, 0x00, // dscale
+			0x00, 0x01, // digits
+		}},
+		{"-0.00012345", []byte{
+			0x00, 0x02, // ndigits
+			0xff, 0xff, // weight
+			0x40, 0x00, // sign
+			0x00, 0x08, // dscale
+			0x00, 0x01, 0x09, 0x29, // digits
+		}},
+	}
+
+	for _, s := range samples {
+		t.Run(s.decimal, func(t *testing.T) {
+			d, err := types.ParseDecimal(s.decimal)
+			require.Nil(t, err)
+			assert.Equal(t, s.data, encodeDecimalData(t, d))
+
+			var result types.Decimal
+			(&decimalCodec{}).Decode(
+				buff.SimpleReader(s.data),
+				unsafe.Pointer(&result),
+			)
+			assert.Equal(t, d, result)
+			assert.Equal(t, s.decimal, result.String())
+		})
+	}
+}
+
+func TestDecimalCodecRoundTrip(t *testing.T) {
+	samples := []string{
+		"1", "-1", "9999", "10000", "-10001", "0.5", "0.05", "123.4567",
+		"1234.56789", "-99999999.99999999", "1e40", "1.000000000000000001",
+		"31415926535897932384626433832795028841971.6939937510",
+	}
+
+	for _, s := range samples {
+		t.Run(s, func(t *testing.T) {
+			d, err := types.ParseDecimal(s)
+			require.Nil(t, err)
+
+			var result types.Decimal
+			(&decimalCodec{}).Decode(
+				buff.SimpleReader(encodeDecimalData(t, d)),
+				unsafe.Pointer(&result),
+			)
+			assert.Equal(t, d, result)
+		})
+	}
+}
+
+func TestDecimalCodecWrongType(t *testing.T) {
+	w := buff.NewWriter(nil)
+	err := (&decimalCodec{}).Encode(w, 1.5, Path("args[0]"))
+	assert.EqualError(
+		t,
+		err,
+		"expected args[0] to be edgedb.Decimal got float64",
+	)
+}
@@ -19,6 +19,7 @@ package codecs
 import (
 	"fmt"
 	"reflect"
+	"strconv"
 	"unsafe"
 
 	"github.com/edgedb/edgedb-go/internal/buff"
@@ -42,6 +43,13 @@ func buildObjectDecoder(
 
 	for i, field := range desc.Fields {
 		sf, ok := marshal.StructField(typ, field.Name)
+		if !ok && field.Flags&descriptor.FlagImplicit != 0 {
+			// implicit fields like __tname__ are only decoded
+			// if the struct has a field for them.
+			fields[i] = &DecoderField{name: field.Name}
+			continue
+		}
+
 		if !ok {
 			return nil, fmt.Errorf(
 				"expected %v to have a field named %q", path, field.Name,
@@ -89,12 +97,50 @@ func (c *objectDecoder) Decode(r *buff.Reader, out unsafe.Pointer) {
 		r.Discard(4) // reserved
 
 		elmLen := r.PopUint32()
+		if field.decoder == nil {
+			if elmLen != 0xffffffff {
+				r.Discard(int(elmLen))
+			}
+			continue
+		}
+
 		if elmLen == 0xffffffff {
 			// element length -1 means missing field
 			// https://www.edgedb.com/docs/internals/protocol/dataformats
+			decodeMissing(field.decoder, pAdd(out, field.offset))
 			continue
 		}
 
 		field.decoder.Decode(r.PopSlice(elmLen), pAdd(out, field.offset))
 	}
 }
+
+// buildArgEncoder builds an encoder for query arguments.
+// Since protocol 1.0 arguments are described by an object descriptor.
+// Positional arguments are named "0", "1", ...
+func buildArgEncoder(desc descriptor.Descriptor) (Encoder, error) {
+	fields := make([]*EncoderField, len(desc.Fields))
+	positional := true
+
+	for i, field := range desc.Fields {
+		if field.Name != strconv.Itoa(i) {
+			positional = false
+		}
+
+		encoder, err := BuildEncoder(field.Desc)
+		if err != nil {
+			return nil, err
+		}
+
+		fields[i] = &EncoderField{
+			name:    field.Name,
+			encoder: encoder,
+		}
+	}
+
+	if positional {
+		return &tupleEncoder{desc.ID, fields}, nil
+	}
+
+	return &namedTupleEncoder{desc.ID, fields}, nil
+}
@@ -0,0 +1,102 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codecs
+
+import (
+	"reflect"
+	"testing"
+	"unsafe"
+
+	"github.com/edgedb/edgedb-go/internal/buff"
+	"github.com/edgedb/edgedb-go/internal/descriptor"
+	types "github.com/edgedb/edgedb-go/internal/edgedbtypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var typeNameObjectFixture = descriptor.Descriptor{
+	Type: descriptor.Object,
+	ID:   types.UUID{1},
+	Fields: []*descriptor.Field{
+		{
+			Name:  "__tname__",
+			Flags: descriptor.FlagImplicit,
+			Desc: descriptor.Descriptor{
+				Type: descriptor.BaseScalar,
+				ID:   strID,
+			},
+		},
+		{
+			Name: "name",
+			Desc: descriptor.Descriptor{
+				Type: descriptor.BaseScalar,
+				ID:   strID,
+			},
+		},
+	},
+}
+
+var typeNameObjectData = []byte{
+	0, 0, 0, 2, // element count
+	0, 0, 0, 0, // reserved
+	0, 0, 0, 9, // element length
+	'd', 'e', 'f', 'a', 'u', 'l', 't', ':', ':',
+	0, 0, 0, 0, // reserved
+	0, 0, 0, 3, // element length
+	'b', 'o', 'b',
+}
+
+func TestDecodeObjectTypeName(t *testing.T) {
+	type Result struct {
+		TypeName string `edgedb:"__tname__"`
+		Name     string `edgedb:"name"`
+	}
+
+	var result Result
+	decoder, err := BuildDecoder(
+		typeNameObjectFixture,
+		reflect.TypeOf(result),
+		Path("out"),
+	)
+	require.Nil(t, err)
+
+	decoder.Decode(
+		buff.SimpleReader(typeNameObjectData),
+		unsafe.Pointer(&result),
+	)
+	assert.Equal(t, Result{TypeName: "default::", Name: "bob"}, result)
+}
+
+func TestDecodeObjectSkipsMissingImplicitFields(t *testing.T) {
+	type Result struct {
+		Name string `edgedb:"name"`
+	}
+
+	var result Result
+	decoder, err := BuildDecoder(
+		typeNameObjectFixture,
+		reflect.TypeOf(result),
+		Path("out"),
+	)
+	require.Nil(t, err)
+
+	decoder.Decode(
+		buff.SimpleReader(typeNameObjectData),
+		unsafe.Pointer(&result),
+	)
+	assert.Equal(t, Result{Name: "bob"}, result)
+}
@@ -0,0 +1,170 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codecs
+
+import (
+	"reflect"
+	"unsafe"
+
+	"github.com/edgedb/edgedb-go/internal/buff"
+	"github.com/edgedb/edgedb-go/internal/descriptor"
+	types "github.com/edgedb/edgedb-go/internal/edgedbtypes"
+)
+
+// optionalTypes are the edgedbtypes Optional types.
+// Their memory layout is struct { val T; isSet bool }.
+var optionalTypes = map[reflect.Type]bool{
+	reflect.TypeOf(types.OptionalBool{}):             true,
+	reflect.TypeOf(types.OptionalBytes{}):            true,
+	reflect.TypeOf(types.OptionalStr{}):              true,
+	reflect.TypeOf(types.OptionalInt16{}):            true,
+	reflect.TypeOf(types.OptionalInt32{}):            true,
+	reflect.TypeOf(types.OptionalInt64{}):            true,
+	reflect.TypeOf(types.OptionalFloat32{}):          true,
+	reflect.TypeOf(types.OptionalFloat64{}):          true,
+	reflect.TypeOf(types.OptionalUUID{}):             true,
+	reflect.TypeOf(types.OptionalDateTime{}):         true,
+	reflect.TypeOf(types.OptionalLocalDateTime{}):    true,
+	reflect.TypeOf(types.OptionalLocalDate{}):        true,
+	reflect.TypeOf(types.OptionalLocalTime{}):        true,
+	reflect.TypeOf(types.OptionalDuration{}):         true,
+	reflect.TypeOf(types.OptionalRelativeDuration{}): true,
+	reflect.TypeOf(types.OptionalBigInt{}):           true,
+	reflect.TypeOf(types.OptionalDecimal{}):          true,
+}
+
+// missingDecoder is implemented by decoders
+// that record when a value is missing.
+type missingDecoder interface {
+	// DecodeMissing is called instead of Decode
+	// when the element length is -1.
+	DecodeMissing(out unsafe.Pointer)
+}
+
+// decodeMissing marks the value at out as missing
+// if the decoder supports missing values.
+func decodeMissing(decoder Decoder, out unsafe.Pointer) {
+	if d, ok := decoder.(missingDecoder); ok {
+		d.DecodeMissing(out)
+	}
+}
+
+// buildPointerDecoder builds a decoder for pointers
+// that are nil when the value is missing.
+func buildPointerDecoder(
+	desc descriptor.Descriptor,
+	typ reflect.Type,
+	path Path,
+) (Decoder, error) {
+	child, err := BuildDecoder(desc, typ.Elem(), path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pointerDecoder{child, typ.Elem()}, nil
+}
+
+type pointerDecoder struct {
+	child Decoder
+	typ   reflect.Type
+}
+
+func (c *pointerDecoder) DescriptorID() types.UUID {
+	return c.child.DescriptorID()
+}
+
+func (c *pointerDecoder) Decode(r *buff.Reader, out unsafe.Pointer) {
+	// a new value is allocated
+	// so that values shared with previous results are not modified.
+	ptr := unsafe.Pointer(reflect.New(c.typ).Pointer())
+	c.child.Decode(r, ptr)
+	*(*unsafe.Pointer)(out) = ptr
+}
+
+func (c *pointerDecoder) DecodeMissing(out unsafe.Pointer) {
+	*(*unsafe.Pointer)(out) = nil
+}
+
+// buildOptionalDecoder builds a decoder for the edgedbtypes Optional types.
+func buildOptionalDecoder(
+	desc descriptor.Descriptor,
+	typ reflect.Type,
+	path Path,
+) (Decoder, error) {
+	child, err := BuildDecoder(desc, typ.Field(0).Type, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &optionalDecoder{child, typ, typ.Field(1).Offset}, nil
+}
+
+type optionalDecoder struct {
+	child Decoder
+	typ   reflect.Type
+
+	// isSetOffset is the offset of the isSet field.
+	isSetOffset uintptr
+}
+
+func (c *optionalDecoder) DescriptorID() types.UUID {
+	return c.child.DescriptorID()
+}
+
+func (c *optionalDecoder) Decode(r *buff.Reader, out unsafe.Pointer) {
+	c.child.Decode(r, out)
+	*(*bool)(pAdd(out, c.isSetOffset)) = true
+}
+
+func (c *optionalDecoder) DecodeMissing(out unsafe.Pointer) {
+	reflect.NewAt(c.typ, out).Elem().Set(reflect.Zero(c.typ))
+}
+
+// optionalEncoder encodes the edgedbtypes Optional types
+// as well as the values its child accepts.
+// Missing values are encoded as element length -1.
+type optionalEncoder struct {
+	child Encoder
+}
+
+func (c *optionalEncoder) DescriptorID() types.UUID {
+	return c.child.DescriptorID()
+}
+
+func (c *optionalEncoder) Encode(
+	w *buff.Writer,
+	val interface{},
+	path Path,
+) error {
+	typ := reflect.TypeOf(val)
+	if !optionalTypes[typ] {
+		return c.child.Encode(w, val, path)
+	}
+
+	// copy the value to read its fields through the memory layout.
+	cpy := reflect.New(typ)
+	cpy.Elem().Set(reflect.ValueOf(val))
+	ptr := unsafe.Pointer(cpy.Pointer())
+
+	if !*(*bool)(pAdd(ptr, typ.Field(1).Offset)) {
+		w.PushUint32(0xffffffff) // missing value
+		return nil
+	}
+
+	in := reflect.NewAt(typ.Field(0).Type, ptr).Elem().Interface()
+	return c.child.Encode(w, in, path)
+}
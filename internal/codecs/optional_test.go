@@ -0,0 +1,219 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codecs
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"unsafe"
+
+	"github.com/edgedb/edgedb-go/internal/buff"
+	"github.com/edgedb/edgedb-go/internal/descriptor"
+	types "github.com/edgedb/edgedb-go/internal/edgedbtypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scalarField(name string, id types.UUID) *descriptor.Field {
+	return &descriptor.Field{
+		Name: name,
+		Desc: descriptor.Descriptor{Type: descriptor.BaseScalar, ID: id},
+	}
+}
+
+var optionalObjectFixture = descriptor.Descriptor{
+	Type: descriptor.Object,
+	ID:   types.UUID{2},
+	Fields: []*descriptor.Field{
+		scalarField("name", strID),
+		scalarField("email", strID),
+		scalarField("age", int64ID),
+	},
+}
+
+var optionalObjectPresent = []byte{
+	0, 0, 0, 3, // element count
+	0, 0, 0, 0, // reserved
+	0, 0, 0, 3, // element length
+	'b', 'o', 'b',
+	0, 0, 0, 0, // reserved
+	0, 0, 0, 1, // element length
+	'@',
+	0, 0, 0, 0, // reserved
+	0, 0, 0, 8, // element length
+	0, 0, 0, 0, 0, 0, 0, 42,
+}
+
+var optionalObjectMissing = []byte{
+	0, 0, 0, 3, // element count
+	0, 0, 0, 0, // reserved
+	0, 0, 0, 3, // element length
+	'b', 'o', 'b',
+	0, 0, 0, 0, // reserved
+	0xff, 0xff, 0xff, 0xff, // missing
+	0, 0, 0, 0, // reserved
+	0xff, 0xff, 0xff, 0xff, // missing
+}
+
+func TestDecodeOptionalFields(t *testing.T) {
+	type Result struct {
+		Name  string              `edgedb:"name"`
+		Email *string             `edgedb:"email"`
+		Age   types.OptionalInt64 `edgedb:"age"`
+	}
+
+	var result Result
+	decoder, err := BuildDecoder(
+		optionalObjectFixture,
+		reflect.TypeOf(result),
+		Path("out"),
+	)
+	require.Nil(t, err)
+
+	decoder.Decode(
+		buff.SimpleReader(optionalObjectPresent),
+		unsafe.Pointer(&result),
+	)
+	require.NotNil(t, result.Email)
+	assert.Equal(t, "@", *result.Email)
+	age, ok := result.Age.Get()
+	assert.True(t, ok)
+	assert.Equal(t, int64(42), age)
+
+	// missing values replace the previous values.
+	email := result.Email
+	decoder.Decode(
+		buff.SimpleReader(optionalObjectMissing),
+		unsafe.Pointer(&result),
+	)
+	assert.Equal(t, Result{Name: "bob"}, result)
+	assert.Equal(t, "@", *email, "previous value was modified")
+}
+
+func TestDecodeOptionalLink(t *testing.T) {
+	type Friend struct {
+		Name string `edgedb:"name"`
+	}
+
+	type Result struct {
+		Friend *Friend `edgedb:"friend"`
+	}
+
+	desc := descriptor.Descriptor{
+		Type: descriptor.Object,
+		ID:   types.UUID{3},
+		Fields: []*descriptor.Field{{
+			Name: "friend",
+			Desc: descriptor.Descriptor{
+				Type:   descriptor.Object,
+				ID:     types.UUID{4},
+				Fields: []*descriptor.Field{scalarField("name", strID)},
+			},
+		}},
+	}
+
+	var result Result
+	decoder, err := BuildDecoder(desc, reflect.TypeOf(result), Path("out"))
+	require.Nil(t, err)
+
+	decoder.Decode(buff.SimpleReader([]byte{
+		0, 0, 0, 1, // element count
+		0, 0, 0, 0, // reserved
+		0, 0, 0, 15, // element length
+		0, 0, 0, 1, // element count
+		0, 0, 0, 0, // reserved
+		0, 0, 0, 3, // element length
+		'b', 'o', 'b',
+	}), unsafe.Pointer(&result))
+	require.NotNil(t, result.Friend)
+	assert.Equal(t, Friend{Name: "bob"}, *result.Friend)
+
+	decoder.Decode(buff.SimpleReader([]byte{
+		0, 0, 0, 1, // element count
+		0, 0, 0, 0, // reserved
+		0xff, 0xff, 0xff, 0xff, // missing
+	}), unsafe.Pointer(&result))
+	assert.Nil(t, result.Friend)
+}
+
+func TestDecodeOptionalWrongType(t *testing.T) {
+	type Result struct {
+		Name types.OptionalInt64 `edgedb:"name"`
+	}
+
+	_, err := BuildDecoder(
+		optionalObjectFixture,
+		reflect.TypeOf(Result{}),
+		Path("out"),
+	)
+	assert.EqualError(t, err, "expected out.name to be string got int64")
+}
+
+func TestEncodeOptionalArguments(t *testing.T) {
+	encoder, err := BuildEncoder(descriptor.Descriptor{
+		Type: descriptor.Tuple,
+		ID:   types.UUID{5},
+		Fields: []*descriptor.Field{
+			scalarField("0", strID),
+			scalarField("1", int64ID),
+			scalarField("2", int64ID),
+		},
+	})
+	require.Nil(t, err)
+
+	w := buff.NewWriter(nil)
+	w.BeginMessage(0)
+	err = encoder.Encode(
+		w,
+		[]interface{}{
+			types.NewOptionalStr("a"),
+			types.OptionalInt64{},
+			int64(7),
+		},
+		Path("args"),
+	)
+	require.Nil(t, err)
+	w.EndMessage()
+
+	var buf bytes.Buffer
+	require.Nil(t, w.Send(&buf))
+
+	expected := []byte{
+		0, 0, 0, 0x2d, // message length
+		0, 0, 0, 0x25, // data length
+		0, 0, 0, 3, // element count
+		0, 0, 0, 0, // reserved
+		0, 0, 0, 1, // element length
+		'a',
+		0, 0, 0, 0, // reserved
+		0xff, 0xff, 0xff, 0xff, // missing
+		0, 0, 0, 0, // reserved
+		0, 0, 0, 8, // element length
+		0, 0, 0, 0, 0, 0, 0, 7,
+	}
+	assert.Equal(t, expected, buf.Bytes()[1:])
+
+	w = buff.NewWriter(nil)
+	w.BeginMessage(0)
+	err = encoder.Encode(
+		w,
+		[]interface{}{"a", types.NewOptionalStr("b"), nil},
+		Path("args"),
+	)
+	assert.EqualError(t, err, "expected args[1] to be int64 got string")
+}
@@ -148,6 +148,7 @@ func (c *tupleDecoder) Decode(r *buff.Reader, out unsafe.Pointer) {
 
 		elmLen := r.PopUint32()
 		if elmLen == 0xffffffff {
+			decodeMissing(field.decoder, pAdd(out, field.offset))
 			continue
 		}
 
@@ -17,6 +17,7 @@
 package descriptor
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 
@@ -28,6 +29,10 @@ import (
 // https://www.edgedb.com/docs/internals/protocol/typedesc#type-descriptors
 var IDZero = types.UUID{}
 
+// IDEmptyTuple is descriptor ID 00000000-0000-0000-0000-0000000000FF
+// it is the input descriptor of queries that take no arguments.
+var IDEmptyTuple = types.UUID{15: 0xff}
+
 // Type represents a descriptor type.
 type Type uint8
 
@@ -55,6 +60,17 @@ const (
 
 	// Enum represents the enum descriptor type.
 	Enum
+
+	// InputShape represents the input shape descriptor type.
+	// It is only used by protocol 1.0 and later.
+	InputShape
+)
+
+// Object shape element flags
+const (
+	FlagImplicit     = 1 << 0
+	FlagLinkProperty = 1 << 1
+	FlagLink         = 1 << 2
 )
 
 // Descriptor is a type descriptor
@@ -69,10 +85,18 @@ type Descriptor struct {
 type Field struct {
 	Name string
 	Desc Descriptor
+
+	// Flags and Cardinality are only set for object shape elements.
+	// Cardinality is only sent by protocol 1.0 and later.
+	Flags       uint32
+	Cardinality uint8
 }
 
 // Pop builds a descriptor tree from a describe statement type description.
-func Pop(r *buff.Reader) Descriptor {
+// protocolMajor is the major version of the protocol in use,
+// the layout of object shapes changed in protocol 1.0.
+// An error is returned for descriptor types that are not supported.
+func Pop(r *buff.Reader, protocolMajor uint16) (Descriptor, error) {
 	descriptors := []Descriptor{}
 
 	for len(r.Buf) > 0 {
@@ -82,11 +106,11 @@ func Pop(r *buff.Reader) Descriptor {
 
 		switch typ {
 		case Set:
-			fields := []*Field{{"", descriptors[r.PopUint16()]}}
+			fields := []*Field{{Desc: descriptors[r.PopUint16()]}}
 			desc = Descriptor{Set, id, fields}
-		case Object:
-			fields := objectFields(r, descriptors)
-			desc = Descriptor{Object, id, fields}
+		case Object, InputShape:
+			fields := objectFields(r, descriptors, protocolMajor)
+			desc = Descriptor{typ, id, fields}
 		case BaseScalar:
 			desc = Descriptor{BaseScalar, id, nil}
 		case Scalar:
@@ -98,8 +122,10 @@ func Pop(r *buff.Reader) Descriptor {
 			fields := namedTupleFields(r, descriptors)
 			desc = Descriptor{typ, id, fields}
 		case Array:
-			fields := []*Field{{"", descriptors[r.PopUint16()]}}
-			assertArrayDimensions(r)
+			fields := []*Field{{Desc: descriptors[r.PopUint16()]}}
+			if err := assertArrayDimensions(r); err != nil {
+				return Descriptor{}, err
+			}
 			desc = Descriptor{typ, id, fields}
 		case Enum:
 			discardEnumMemberNames(r)
@@ -111,24 +137,43 @@ func Pop(r *buff.Reader) Descriptor {
 				break
 			}
 
-			panic(fmt.Sprintf("unknown descriptor type 0x%x", typ))
+			return Descriptor{}, fmt.Errorf(
+				"unknown descriptor type 0x%x", typ,
+			)
 		}
 
 		descriptors = append(descriptors, desc)
 	}
 
-	return descriptors[len(descriptors)-1]
+	return descriptors[len(descriptors)-1], nil
 }
 
-func objectFields(r *buff.Reader, descriptors []Descriptor) []*Field {
+func objectFields(
+	r *buff.Reader,
+	descriptors []Descriptor,
+	protocolMajor uint16,
+) []*Field {
 	n := int(r.PopUint16())
 	fields := make([]*Field, n)
 
 	for i := 0; i < n; i++ {
-		r.Discard(1) // flags
+		var (
+			flags uint32
+			card  uint8
+		)
+
+		if protocolMajor == 0 {
+			flags = uint32(r.PopUint8())
+		} else {
+			flags = r.PopUint32()
+			card = r.PopUint8()
+		}
+
 		fields[i] = &Field{
-			Name: r.PopString(),
-			Desc: descriptors[r.PopUint16()],
+			Name:        r.PopString(),
+			Desc:        descriptors[r.PopUint16()],
+			Flags:       flags,
+			Cardinality: card,
 		}
 	}
 
@@ -163,13 +208,16 @@ func namedTupleFields(r *buff.Reader, descriptors []Descriptor) []*Field {
 	return fields
 }
 
-func assertArrayDimensions(r *buff.Reader) {
+func assertArrayDimensions(r *buff.Reader) error {
 	n := int(r.PopUint16()) // number of array dimensions
 	if n == 0 {
-		panic("too few array dimensions: expected at least 1, got 0")
+		return errors.New(
+			"too few array dimensions: expected at least 1, got 0",
+		)
 	}
 
 	r.Discard(4 * n) // array dimension
+	return nil
 }
 
 func discardEnumMemberNames(r *buff.Reader) {
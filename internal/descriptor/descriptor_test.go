@@ -0,0 +1,65 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package descriptor
+
+import (
+	"testing"
+
+	"github.com/edgedb/edgedb-go/internal/buff"
+	types "github.com/edgedb/edgedb-go/internal/edgedbtypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	int64ID = types.UUID{15: 0x05}
+	otherID = types.UUID{0: 0x01}
+)
+
+func TestPopBaseScalar(t *testing.T) {
+	data := append([]byte{0x02}, int64ID[:]...)
+
+	desc, err := Pop(buff.SimpleReader(data), 1)
+	require.Nil(t, err)
+	assert.Equal(t, Descriptor{Type: BaseScalar, ID: int64ID}, desc)
+}
+
+func TestPopUnknownType(t *testing.T) {
+	// a range descriptor is not supported.
+	data := append([]byte{0x02}, int64ID[:]...)
+	data = append(data, 0x09)
+	data = append(data, otherID[:]...)
+	data = append(data, 0x00, 0x00)
+
+	_, err := Pop(buff.SimpleReader(data), 1)
+	assert.EqualError(t, err, "unknown descriptor type 0x9")
+}
+
+func TestPopArrayWithoutDimensions(t *testing.T) {
+	data := append([]byte{0x02}, int64ID[:]...)
+	data = append(data, 0x06)
+	data = append(data, otherID[:]...)
+	data = append(data, 0x00, 0x00) // element type
+	data = append(data, 0x00, 0x00) // dimensions
+
+	_, err := Pop(buff.SimpleReader(data), 1)
+	assert.EqualError(
+		t,
+		err,
+		"too few array dimensions: expected at least 1, got 0",
+	)
+}
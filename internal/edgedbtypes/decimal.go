@@ -0,0 +1,214 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedbtypes
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// The limits of the decimal wire format
+// which has a uint16 scale and an int16 weight of base 10,000 digits.
+const (
+	maxDecimalScale = math.MaxUint16
+	maxDecimalWhole = (math.MaxInt16 + 1) * 4
+)
+
+var (
+	errMalformedDecimal  = errors.New("malformed edgedb.Decimal")
+	errDecimalOutOfRange = errors.New("edgedb.Decimal out of range")
+)
+
+// NewDecimal returns the Decimal unscaled * 10^-scale.
+// A negative scale multiplies unscaled by a power of ten.
+// An error is returned if the Decimal would have more than 65,535 digits
+// after the decimal point or more than 131,072 digits before it.
+func NewDecimal(unscaled *big.Int, scale int32) (Decimal, error) {
+	digits := unscaled.String()
+	neg := strings.HasPrefix(digits, "-")
+	digits = strings.TrimPrefix(digits, "-")
+
+	return newDecimal(neg, digits, int64(scale))
+}
+
+// NewDecimalFromFloat returns the shortest Decimal
+// that rounds to f at f's precision.
+func NewDecimalFromFloat(f *big.Float) (Decimal, error) {
+	if f.IsInf() {
+		return Decimal{}, errors.New("edgedb.Decimal can not be infinite")
+	}
+
+	// f's binary exponent is checked before formatting
+	// so that huge or tiny floats are not expanded.
+	// 2^4 > 10 so f is out of range if its exponent
+	// is more than 4 times the limit in decimal digits.
+	if exp := f.MantExp(nil); exp > 4*maxDecimalWhole ||
+		f.Sign() != 0 && exp < -4*maxDecimalScale {
+		return Decimal{}, errDecimalOutOfRange
+	}
+
+	return ParseDecimal(f.Text('f', -1))
+}
+
+// ParseDecimal parses s into a Decimal or returns an error.
+// s is a decimal number with an optional sign, fraction and exponent
+// like -12.50 or 1.25e3. The number of digits after the decimal point
+// is kept as the Decimal's scale.
+// The same limits as NewDecimal apply.
+func ParseDecimal(s string) (Decimal, error) {
+	var neg bool
+	switch {
+	case strings.HasPrefix(s, "-"):
+		neg = true
+		s = s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	var exp int64
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		var err error
+		exp, err = strconv.ParseInt(s[i+1:], 10, 32)
+		if err != nil {
+			return Decimal{}, errMalformedDecimal
+		}
+
+		s = s[:i]
+	}
+
+	whole, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		whole, frac = s[:i], s[i+1:]
+	}
+
+	if whole == "" && frac == "" || !isDigits(whole) || !isDigits(frac) {
+		return Decimal{}, errMalformedDecimal
+	}
+
+	return newDecimal(neg, whole+frac, int64(len(frac))-exp)
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// newDecimal normalizes the leading zeros, sign of zero
+// and negative scales or returns an error if the Decimal is out of range.
+func newDecimal(neg bool, digits string, scale int64) (Decimal, error) {
+	digits = strings.TrimLeft(digits, "0")
+	if digits == "" {
+		neg = false
+	}
+
+	if scale > maxDecimalScale ||
+		digits != "" && int64(len(digits))-scale > maxDecimalWhole {
+		return Decimal{}, errDecimalOutOfRange
+	}
+
+	if scale < 0 {
+		if digits != "" {
+			digits += strings.Repeat("0", int(-scale))
+		}
+
+		scale = 0
+	}
+
+	return Decimal{digits: digits, scale: int32(scale), neg: neg}, nil
+}
+
+// Decimal is an exact decimal number.
+// The number of digits after the decimal point is preserved,
+// so 1.5 and 1.50 are different Decimals with the same value.
+// The zero value is 0.
+// https://www.edgedb.com/docs/datamodel/scalars/numeric#type::std::decimal
+type Decimal struct {
+	// digits are the digits of the absolute value
+	// without the decimal point or leading zeros.
+	digits string
+	scale  int32
+	neg    bool
+}
+
+func (d Decimal) String() string {
+	digits := d.digits
+	if n := int(d.scale) + 1 - len(digits); n > 0 {
+		digits = strings.Repeat("0", n) + digits
+	}
+
+	if d.scale > 0 {
+		i := len(digits) - int(d.scale)
+		digits = digits[:i] + "." + digits[i:]
+	}
+
+	if d.neg {
+		return "-" + digits
+	}
+
+	return digits
+}
+
+// Unscaled returns unscaled and scale
+// such that d is unscaled * 10^-scale.
+func (d Decimal) Unscaled() (unscaled *big.Int, scale int32) {
+	unscaled = &big.Int{}
+	if d.digits != "" {
+		unscaled.SetString(d.digits, 10)
+	}
+
+	if d.neg {
+		unscaled.Neg(unscaled)
+	}
+
+	return unscaled, d.scale
+}
+
+// Float returns d rounded to the nearest *big.Float with precision prec.
+// If prec is 0 the precision is 64.
+func (d Decimal) Float(prec uint) *big.Float {
+	f, _, err := big.ParseFloat(d.String(), 10, prec, big.ToNearestEven)
+	if err != nil {
+		panic(fmt.Sprintf("edgedb.Decimal %v: %v", d, err))
+	}
+
+	return f
+}
+
+// MarshalText returns the decimal as a byte string.
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText unmarshals the decimal from a string.
+func (d *Decimal) UnmarshalText(b []byte) error {
+	tmp, err := ParseDecimal(string(b))
+	if err != nil {
+		return err
+	}
+
+	*d = tmp
+	return nil
+}
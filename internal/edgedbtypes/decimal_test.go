@@ -0,0 +1,174 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedbtypes
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecimalParse(t *testing.T) {
+	samples := []struct {
+		input    string
+		expected string
+	}{
+		{"0", "0"},
+		{"-0", "0"},
+		{"0.00", "0.00"},
+		{"007", "7"},
+		{"+1.5", "1.5"},
+		{"-15000.6250000", "-15000.6250000"},
+		{".25", "0.25"},
+		{"1.", "1"},
+		{"-0.001", "-0.001"},
+		{"1.25e3", "1250"},
+		{"1.25E-3", "0.00125"},
+		{"12e-1", "1.2"},
+		{"123456789012345678901234567890.123456789", "123456789012345678901234567890.123456789"}, // nolint:lll
+	}
+
+	for _, s := range samples {
+		t.Run(s.input, func(t *testing.T) {
+			d, err := ParseDecimal(s.input)
+			require.Nil(t, err)
+			assert.Equal(t, s.expected, d.String())
+		})
+	}
+}
+
+func TestDecimalParseInvalid(t *testing.T) {
+	samples := []string{"", "-", ".", "1.2.3", "1e", "e5", "abc", "NaN"}
+	for _, s := range samples {
+		t.Run(s, func(t *testing.T) {
+			_, err := ParseDecimal(s)
+			assert.EqualError(t, err, "malformed edgedb.Decimal")
+		})
+	}
+}
+
+func TestDecimalOutOfRange(t *testing.T) {
+	samples := []struct {
+		name  string
+		input string
+	}{
+		{"huge exponent", "1e2000000000"},
+		{"tiny exponent", "1e-2000000000"},
+		{"too many whole digits", "1e131072"},
+		{"too many fraction digits", "0." + strings.Repeat("0", 65535) + "1"},
+	}
+
+	for _, s := range samples {
+		t.Run(s.name, func(t *testing.T) {
+			_, err := ParseDecimal(s.input)
+			assert.EqualError(t, err, "edgedb.Decimal out of range")
+		})
+	}
+
+	_, err := NewDecimal(big.NewInt(1), math.MinInt32)
+	assert.EqualError(t, err, "edgedb.Decimal out of range")
+
+	_, err = NewDecimal(big.NewInt(1), math.MaxInt32)
+	assert.EqualError(t, err, "edgedb.Decimal out of range")
+
+	huge := (&big.Float{}).SetMantExp(big.NewFloat(1), 1<<30)
+	_, err = NewDecimalFromFloat(huge)
+	assert.EqualError(t, err, "edgedb.Decimal out of range")
+
+	tiny := (&big.Float{}).SetMantExp(big.NewFloat(1), -1<<30)
+	_, err = NewDecimalFromFloat(tiny)
+	assert.EqualError(t, err, "edgedb.Decimal out of range")
+
+	// the limits of the wire format are in range.
+	d, err := ParseDecimal("1e131071")
+	require.Nil(t, err)
+	assert.Equal(t, 131072, len(d.String()))
+
+	d, err = ParseDecimal("0e2000000000")
+	require.Nil(t, err)
+	assert.Equal(t, "0", d.String())
+
+	d, err = ParseDecimal("1e-65535")
+	require.Nil(t, err)
+	assert.Equal(t, 65537, len(d.String()))
+}
+
+func TestDecimalZeroValue(t *testing.T) {
+	var d Decimal
+	assert.Equal(t, "0", d.String())
+
+	unscaled, scale := d.Unscaled()
+	assert.Equal(t, big.NewInt(0), unscaled)
+	assert.Equal(t, int32(0), scale)
+}
+
+func TestDecimalUnscaled(t *testing.T) {
+	d, err := NewDecimal(big.NewInt(-150_006_250_000), 7)
+	require.Nil(t, err)
+	assert.Equal(t, "-15000.6250000", d.String())
+
+	unscaled, scale := d.Unscaled()
+	assert.Equal(t, big.NewInt(-150_006_250_000), unscaled)
+	assert.Equal(t, int32(7), scale)
+
+	d, err = NewDecimal(big.NewInt(15), -3)
+	require.Nil(t, err)
+	assert.Equal(t, "15000", d.String())
+
+	unscaled, scale = d.Unscaled()
+	assert.Equal(t, big.NewInt(15_000), unscaled)
+	assert.Equal(t, int32(0), scale)
+}
+
+func TestDecimalFloat(t *testing.T) {
+	d, err := NewDecimalFromFloat(big.NewFloat(0.1))
+	require.Nil(t, err)
+	assert.Equal(t, "0.1", d.String())
+
+	d, err = NewDecimalFromFloat(big.NewFloat(-1234.5))
+	require.Nil(t, err)
+	assert.Equal(t, "-1234.5", d.String())
+
+	f, _ := d.Float(0).Float64()
+	assert.Equal(t, -1234.5, f)
+	assert.Equal(t, uint(64), d.Float(0).Prec())
+
+	inf := (&big.Float{}).SetInf(false)
+	_, err = NewDecimalFromFloat(inf)
+	assert.EqualError(t, err, "edgedb.Decimal can not be infinite")
+}
+
+func TestDecimalJSON(t *testing.T) {
+	d, err := ParseDecimal("-12.50")
+	require.Nil(t, err)
+
+	bts, err := json.Marshal(d)
+	require.Nil(t, err)
+	assert.Equal(t, `"-12.50"`, string(bts))
+
+	var result Decimal
+	require.Nil(t, json.Unmarshal(bts, &result))
+	assert.Equal(t, d, result)
+
+	err = json.Unmarshal([]byte(`"twelve"`), &result)
+	assert.EqualError(t, err, "malformed edgedb.Decimal")
+}
@@ -0,0 +1,487 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedbtypes
+
+import (
+	"math/big"
+	"time"
+)
+
+// The Optional types hold a value that may be missing.
+// They are used for shape fields and query arguments
+// that are not required. The zero value is missing.
+// Their memory layout is struct { val T; isSet bool }
+// which the codecs depend on.
+
+// NewOptionalBool returns an OptionalBool set to v.
+func NewOptionalBool(v bool) OptionalBool {
+	return OptionalBool{val: v, isSet: true}
+}
+
+// OptionalBool is a bool that may be missing.
+type OptionalBool struct {
+	val   bool
+	isSet bool
+}
+
+// Get returns the value and true, or false if the value is missing.
+func (o OptionalBool) Get() (bool, bool) {
+	return o.val, o.isSet
+}
+
+// Set sets the value.
+func (o *OptionalBool) Set(val bool) {
+	o.val = val
+	o.isSet = true
+}
+
+// Unset marks the value as missing.
+func (o *OptionalBool) Unset() {
+	*o = OptionalBool{}
+}
+
+// NewOptionalBytes returns an OptionalBytes set to v.
+func NewOptionalBytes(v []byte) OptionalBytes {
+	return OptionalBytes{val: v, isSet: true}
+}
+
+// OptionalBytes is a []byte that may be missing.
+type OptionalBytes struct {
+	val   []byte
+	isSet bool
+}
+
+// Get returns the value and true, or false if the value is missing.
+func (o OptionalBytes) Get() ([]byte, bool) {
+	return o.val, o.isSet
+}
+
+// Set sets the value.
+func (o *OptionalBytes) Set(val []byte) {
+	o.val = val
+	o.isSet = true
+}
+
+// Unset marks the value as missing.
+func (o *OptionalBytes) Unset() {
+	*o = OptionalBytes{}
+}
+
+// NewOptionalStr returns an OptionalStr set to v.
+func NewOptionalStr(v string) OptionalStr {
+	return OptionalStr{val: v, isSet: true}
+}
+
+// OptionalStr is a string that may be missing.
+type OptionalStr struct {
+	val   string
+	isSet bool
+}
+
+// Get returns the value and true, or false if the value is missing.
+func (o OptionalStr) Get() (string, bool) {
+	return o.val, o.isSet
+}
+
+// Set sets the value.
+func (o *OptionalStr) Set(val string) {
+	o.val = val
+	o.isSet = true
+}
+
+// Unset marks the value as missing.
+func (o *OptionalStr) Unset() {
+	*o = OptionalStr{}
+}
+
+// NewOptionalInt16 returns an OptionalInt16 set to v.
+func NewOptionalInt16(v int16) OptionalInt16 {
+	return OptionalInt16{val: v, isSet: true}
+}
+
+// OptionalInt16 is an int16 that may be missing.
+type OptionalInt16 struct {
+	val   int16
+	isSet bool
+}
+
+// Get returns the value and true, or false if the value is missing.
+func (o OptionalInt16) Get() (int16, bool) {
+	return o.val, o.isSet
+}
+
+// Set sets the value.
+func (o *OptionalInt16) Set(val int16) {
+	o.val = val
+	o.isSet = true
+}
+
+// Unset marks the value as missing.
+func (o *OptionalInt16) Unset() {
+	*o = OptionalInt16{}
+}
+
+// NewOptionalInt32 returns an OptionalInt32 set to v.
+func NewOptionalInt32(v int32) OptionalInt32 {
+	return OptionalInt32{val: v, isSet: true}
+}
+
+// OptionalInt32 is an int32 that may be missing.
+type OptionalInt32 struct {
+	val   int32
+	isSet bool
+}
+
+// Get returns the value and true, or false if the value is missing.
+func (o OptionalInt32) Get() (int32, bool) {
+	return o.val, o.isSet
+}
+
+// Set sets the value.
+func (o *OptionalInt32) Set(val int32) {
+	o.val = val
+	o.isSet = true
+}
+
+// Unset marks the value as missing.
+func (o *OptionalInt32) Unset() {
+	*o = OptionalInt32{}
+}
+
+// NewOptionalInt64 returns an OptionalInt64 set to v.
+func NewOptionalInt64(v int64) OptionalInt64 {
+	return OptionalInt64{val: v, isSet: true}
+}
+
+// OptionalInt64 is an int64 that may be missing.
+type OptionalInt64 struct {
+	val   int64
+	isSet bool
+}
+
+// Get returns the value and true, or false if the value is missing.
+func (o OptionalInt64) Get() (int64, bool) {
+	return o.val, o.isSet
+}
+
+// Set sets the value.
+func (o *OptionalInt64) Set(val int64) {
+	o.val = val
+	o.isSet = true
+}
+
+// Unset marks the value as missing.
+func (o *OptionalInt64) Unset() {
+	*o = OptionalInt64{}
+}
+
+// NewOptionalFloat32 returns an OptionalFloat32 set to v.
+func NewOptionalFloat32(v float32) OptionalFloat32 {
+	return OptionalFloat32{val: v, isSet: true}
+}
+
+// OptionalFloat32 is a float32 that may be missing.
+type OptionalFloat32 struct {
+	val   float32
+	isSet bool
+}
+
+// Get returns the value and true, or false if the value is missing.
+func (o OptionalFloat32) Get() (float32, bool) {
+	return o.val, o.isSet
+}
+
+// Set sets the value.
+func (o *OptionalFloat32) Set(val float32) {
+	o.val = val
+	o.isSet = true
+}
+
+// Unset marks the value as missing.
+func (o *OptionalFloat32) Unset() {
+	*o = OptionalFloat32{}
+}
+
+// NewOptionalFloat64 returns an OptionalFloat64 set to v.
+func NewOptionalFloat64(v float64) OptionalFloat64 {
+	return OptionalFloat64{val: v, isSet: true}
+}
+
+// OptionalFloat64 is a float64 that may be missing.
+type OptionalFloat64 struct {
+	val   float64
+	isSet bool
+}
+
+// Get returns the value and true, or false if the value is missing.
+func (o OptionalFloat64) Get() (float64, bool) {
+	return o.val, o.isSet
+}
+
+// Set sets the value.
+func (o *OptionalFloat64) Set(val float64) {
+	o.val = val
+	o.isSet = true
+}
+
+// Unset marks the value as missing.
+func (o *OptionalFloat64) Unset() {
+	*o = OptionalFloat64{}
+}
+
+// NewOptionalUUID returns an OptionalUUID set to v.
+func NewOptionalUUID(v UUID) OptionalUUID {
+	return OptionalUUID{val: v, isSet: true}
+}
+
+// OptionalUUID is a UUID that may be missing.
+type OptionalUUID struct {
+	val   UUID
+	isSet bool
+}
+
+// Get returns the value and true, or false if the value is missing.
+func (o OptionalUUID) Get() (UUID, bool) {
+	return o.val, o.isSet
+}
+
+// Set sets the value.
+func (o *OptionalUUID) Set(val UUID) {
+	o.val = val
+	o.isSet = true
+}
+
+// Unset marks the value as missing.
+func (o *OptionalUUID) Unset() {
+	*o = OptionalUUID{}
+}
+
+// NewOptionalDateTime returns an OptionalDateTime set to v.
+func NewOptionalDateTime(v time.Time) OptionalDateTime {
+	return OptionalDateTime{val: v, isSet: true}
+}
+
+// OptionalDateTime is a time.Time that may be missing.
+type OptionalDateTime struct {
+	val   time.Time
+	isSet bool
+}
+
+// Get returns the value and true, or false if the value is missing.
+func (o OptionalDateTime) Get() (time.Time, bool) {
+	return o.val, o.isSet
+}
+
+// Set sets the value.
+func (o *OptionalDateTime) Set(val time.Time) {
+	o.val = val
+	o.isSet = true
+}
+
+// Unset marks the value as missing.
+func (o *OptionalDateTime) Unset() {
+	*o = OptionalDateTime{}
+}
+
+// NewOptionalLocalDateTime returns an OptionalLocalDateTime set to v.
+func NewOptionalLocalDateTime(v LocalDateTime) OptionalLocalDateTime {
+	return OptionalLocalDateTime{val: v, isSet: true}
+}
+
+// OptionalLocalDateTime is a LocalDateTime that may be missing.
+type OptionalLocalDateTime struct {
+	val   LocalDateTime
+	isSet bool
+}
+
+// Get returns the value and true, or false if the value is missing.
+func (o OptionalLocalDateTime) Get() (LocalDateTime, bool) {
+	return o.val, o.isSet
+}
+
+// Set sets the value.
+func (o *OptionalLocalDateTime) Set(val LocalDateTime) {
+	o.val = val
+	o.isSet = true
+}
+
+// Unset marks the value as missing.
+func (o *OptionalLocalDateTime) Unset() {
+	*o = OptionalLocalDateTime{}
+}
+
+// NewOptionalLocalDate returns an OptionalLocalDate set to v.
+func NewOptionalLocalDate(v LocalDate) OptionalLocalDate {
+	return OptionalLocalDate{val: v, isSet: true}
+}
+
+// OptionalLocalDate is a LocalDate that may be missing.
+type OptionalLocalDate struct {
+	val   LocalDate
+	isSet bool
+}
+
+// Get returns the value and true, or false if the value is missing.
+func (o OptionalLocalDate) Get() (LocalDate, bool) {
+	return o.val, o.isSet
+}
+
+// Set sets the value.
+func (o *OptionalLocalDate) Set(val LocalDate) {
+	o.val = val
+	o.isSet = true
+}
+
+// Unset marks the value as missing.
+func (o *OptionalLocalDate) Unset() {
+	*o = OptionalLocalDate{}
+}
+
+// NewOptionalLocalTime returns an OptionalLocalTime set to v.
+func NewOptionalLocalTime(v LocalTime) OptionalLocalTime {
+	return OptionalLocalTime{val: v, isSet: true}
+}
+
+// OptionalLocalTime is a LocalTime that may be missing.
+type OptionalLocalTime struct {
+	val   LocalTime
+	isSet bool
+}
+
+// Get returns the value and true, or false if the value is missing.
+func (o OptionalLocalTime) Get() (LocalTime, bool) {
+	return o.val, o.isSet
+}
+
+// Set sets the value.
+func (o *OptionalLocalTime) Set(val LocalTime) {
+	o.val = val
+	o.isSet = true
+}
+
+// Unset marks the value as missing.
+func (o *OptionalLocalTime) Unset() {
+	*o = OptionalLocalTime{}
+}
+
+// NewOptionalDuration returns an OptionalDuration set to v.
+func NewOptionalDuration(v Duration) OptionalDuration {
+	return OptionalDuration{val: v, isSet: true}
+}
+
+// OptionalDuration is a Duration that may be missing.
+type OptionalDuration struct {
+	val   Duration
+	isSet bool
+}
+
+// Get returns the value and true, or false if the value is missing.
+func (o OptionalDuration) Get() (Duration, bool) {
+	return o.val, o.isSet
+}
+
+// Set sets the value.
+func (o *OptionalDuration) Set(val Duration) {
+	o.val = val
+	o.isSet = true
+}
+
+// Unset marks the value as missing.
+func (o *OptionalDuration) Unset() {
+	*o = OptionalDuration{}
+}
+
+// NewOptionalRelativeDuration returns an OptionalRelativeDuration set to v.
+func NewOptionalRelativeDuration(v RelativeDuration) OptionalRelativeDuration {
+	return OptionalRelativeDuration{val: v, isSet: true}
+}
+
+// OptionalRelativeDuration is a RelativeDuration that may be missing.
+type OptionalRelativeDuration struct {
+	val   RelativeDuration
+	isSet bool
+}
+
+// Get returns the value and true, or false if the value is missing.
+func (o OptionalRelativeDuration) Get() (RelativeDuration, bool) {
+	return o.val, o.isSet
+}
+
+// Set sets the value.
+func (o *OptionalRelativeDuration) Set(val RelativeDuration) {
+	o.val = val
+	o.isSet = true
+}
+
+// Unset marks the value as missing.
+func (o *OptionalRelativeDuration) Unset() {
+	*o = OptionalRelativeDuration{}
+}
+
+// NewOptionalBigInt returns an OptionalBigInt set to v.
+func NewOptionalBigInt(v *big.Int) OptionalBigInt {
+	return OptionalBigInt{val: v, isSet: true}
+}
+
+// OptionalBigInt is a *big.Int that may be missing.
+type OptionalBigInt struct {
+	val   *big.Int
+	isSet bool
+}
+
+// Get returns the value and true, or false if the value is missing.
+func (o OptionalBigInt) Get() (*big.Int, bool) {
+	return o.val, o.isSet
+}
+
+// Set sets the value.
+func (o *OptionalBigInt) Set(val *big.Int) {
+	o.val = val
+	o.isSet = true
+}
+
+// Unset marks the value as missing.
+func (o *OptionalBigInt) Unset() {
+	*o = OptionalBigInt{}
+}
+
+// NewOptionalDecimal returns an OptionalDecimal set to v.
+func NewOptionalDecimal(v Decimal) OptionalDecimal {
+	return OptionalDecimal{val: v, isSet: true}
+}
+
+// OptionalDecimal is a Decimal that may be missing.
+type OptionalDecimal struct {
+	val   Decimal
+	isSet bool
+}
+
+// Get returns the value and true, or false if the value is missing.
+func (o OptionalDecimal) Get() (Decimal, bool) {
+	return o.val, o.isSet
+}
+
+// Set sets the value.
+func (o *OptionalDecimal) Set(val Decimal) {
+	o.val = val
+	o.isSet = true
+}
+
+// Unset marks the value as missing.
+func (o *OptionalDecimal) Unset() {
+	*o = OptionalDecimal{}
+}
@@ -0,0 +1,46 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedbtypes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptional(t *testing.T) {
+	var o OptionalStr
+	val, ok := o.Get()
+	assert.False(t, ok)
+	assert.Equal(t, "", val)
+
+	o.Set("")
+	val, ok = o.Get()
+	assert.True(t, ok, "the zero value can be set")
+	assert.Equal(t, "", val)
+
+	o = NewOptionalStr("hello")
+	val, ok = o.Get()
+	assert.True(t, ok)
+	assert.Equal(t, "hello", val)
+
+	o.Unset()
+	val, ok = o.Get()
+	assert.False(t, ok)
+	assert.Equal(t, "", val)
+	assert.Equal(t, OptionalStr{}, o)
+}
@@ -21,4 +21,5 @@ const (
 	Binary       = 0x62
 	JSON         = 0x6a
 	JSONElements = 0x4a
+	Null         = 0x6e
 )
@@ -16,12 +16,26 @@
 
 package header
 
-import "encoding/binary"
+import (
+	"encoding/binary"
+	"strconv"
+)
 
 const (
+	// ImplicitLimit adds an implicit LIMIT clause to returned sets.
+	ImplicitLimit = 0xFF01
+
+	// ImplicitTypeNames tells the server to inject object type names.
+	ImplicitTypeNames = 0xFF02
+
+	// ImplicitTypeIDs tells the server to inject object type ids.
+	ImplicitTypeIDs = 0xFF03
+
 	// AllowCapabilities tells the server what capabilities it should allow.
-	AllowCapabilities        = 0xFF04
-	allCapabilities   uint64 = 0xffffffffffffffff
+	AllowCapabilities = 0xFF04
+
+	// AllCapabilities has every capability bit set.
+	AllCapabilities uint64 = 0xffffffffffffffff
 
 	// ExplicitObjectIDs tells the server not to inject object ids.
 	ExplicitObjectIDs = 0xFF05
@@ -31,10 +45,52 @@ const (
 	AllowCapabilitieTransaction uint64 = 0b100
 )
 
+// Compilation flags replace the implicit headers in protocol 1.0.
+const (
+	InjectOutputTypeIDs   uint64 = 1 << 0
+	InjectOutputTypeNames uint64 = 1 << 1
+)
+
+// CompilationFlags returns the compilation flags
+// equivalent to the implicit headers in headers.
+func CompilationFlags(headers map[uint16][]byte) uint64 {
+	var flags uint64
+
+	if string(headers[ImplicitTypeIDs]) == "true" {
+		flags |= InjectOutputTypeIDs
+	}
+
+	if string(headers[ImplicitTypeNames]) == "true" {
+		flags |= InjectOutputTypeNames
+	}
+
+	return flags
+}
+
+// ImplicitLimitOf returns the implicit limit set in headers
+// or 0 if there is no limit.
+func ImplicitLimitOf(headers map[uint16][]byte) uint64 {
+	limit, err := strconv.ParseUint(string(headers[ImplicitLimit]), 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return limit
+}
+
+// AllowedCapabilities returns the capabilities allowed by headers.
+func AllowedCapabilities(headers map[uint16][]byte) uint64 {
+	if val, ok := headers[AllowCapabilities]; ok {
+		return binary.BigEndian.Uint64(val)
+	}
+
+	return AllCapabilities
+}
+
 // NewAllowCapabilitiesWithout returns an AllowCapabilities header value
 // with the bits set in mask masked off.
 func NewAllowCapabilitiesWithout(mask uint64) []byte {
 	bts := make([]byte, 8)
-	binary.BigEndian.PutUint64(bts, allCapabilities^mask)
+	binary.BigEndian.PutUint64(bts, AllCapabilities^mask)
 	return bts
 }
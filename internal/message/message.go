@@ -32,19 +32,23 @@ const (
 	RestoreReady           = 0x2b
 	ServerHandshake        = 0x76
 	ServerKeyData          = 0x4b
+	StateDataDescription   = 0x73
 )
 
 // Message types sent by client
 const (
 	AuthenticationSASLInitialResponse = 0x70
 	AuthenticationSASLResponse        = 0x72
+	CancelRequest                     = 0x63
 	ClientHandshake                   = 0x56
 	DescribeStatement                 = 0x44
 	Dump                              = 0x3e
-	Execute                           = 0x45
+	Execute                           = 0x4f
+	Execute0pX                        = 0x45
 	ExecuteScript                     = 0x51
 	Flush                             = 0x48
 	OptimisticExecute                 = 0x4f
+	Parse                             = 0x50
 	Prepare                           = 0x50
 	Restore                           = 0x3c
 	RestoreBlock                      = 0x3d
@@ -17,8 +17,11 @@
 package edgedb
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"io"
 	"math"
 	"time"
 )
@@ -80,13 +83,75 @@ type Options struct {
 	// without needing specific privileges.
 	Password string
 
+	// PasswordProvider is called for the password
+	// each time a new connection authenticates.
+	// It can be used with credentials that are rotated
+	// while the pool is open.
+	// If PasswordProvider is set, Password and passwords from the dsn,
+	// the credentials file and the environment are ignored.
+	PasswordProvider PasswordProvider
+
 	// ConnectTimeout is used when establishing connections in the background.
 	ConnectTimeout time.Duration
 
+	// Dialer opens the network connections to the server.
+	// It can be used to tunnel connections or to wrap them for testing.
+	// If Dialer is nil a net.Dialer is used.
+	Dialer DialFunc
+
+	// KeepAlive is the interval between TCP keep-alive probes.
+	// If KeepAlive is zero, a default is used.
+	// If KeepAlive is negative, keep-alive probes are disabled.
+	// Has no effect if Dialer is set.
+	KeepAlive time.Duration
+
+	// ReadBufferSize sets the size of the operating system's
+	// receive buffer for TCP connections.
+	// If ReadBufferSize is zero, the system default is used.
+	ReadBufferSize int
+
+	// WriteBufferSize sets the size of the operating system's
+	// transmit buffer for TCP connections.
+	// If WriteBufferSize is zero, the system default is used.
+	WriteBufferSize int
+
+	// Transcript receives a readable record of the messages
+	// sent and received by each connection.
+	// Transcripts can be replayed with ReplayDialer.
+	// Writes to Transcript are serialized.
+	//
+	// Transcripts contain the queries, their arguments and their results
+	// as well as the user and database names.
+	// Passwords and authentication data are replaced with zeros,
+	// but transcripts of sensitive data must not be committed or shared.
+	Transcript io.Writer
+
 	// WaitUntilAvailable determines how long to wait
 	// to reestablish a connection.
 	WaitUntilAvailable time.Duration
 
+	// HostSelection determines the order in which Hosts are tried
+	// when connecting. Hosts that fail to connect are marked down
+	// for a time that doubles with each consecutive failure,
+	// and are skipped until they have recovered unless every host is down.
+	// If HostSelection is empty, HostSelectionOrdered is used.
+	HostSelection HostSelectionMode
+
+	// HostSelector orders the hosts when HostSelection is
+	// HostSelectionCustom.
+	HostSelector HostSelector
+
+	// ReadReplicas are servers that read only queries are sent to.
+	// Each replica is either a DSN or a comma separated list of hosts
+	// with optional ports. Replicas specified as hosts use the other
+	// connection options of the primary.
+	// Query, QueryOne, QueryJSON, QueryOneJSON and read only transactions
+	// use the replicas in turn. They fall back to the primary
+	// if a replica is down or if the query writes data.
+	// Replicas are connected lazily and use the pool options
+	// other than ReadReplicas. Has no effect for single connections.
+	ReadReplicas []string
+
 	// MinConns determines the minimum number of connections.
 	// If MinConns is zero, 1 will be used.
 	// Has no effect for single connections.
@@ -97,10 +162,106 @@ type Options struct {
 	// Has no effect for single connections.
 	MaxConns uint
 
+	// Lazy makes Connect return without connecting to the server.
+	// Connections are established when they are first acquired,
+	// retrying for up to WaitUntilAvailable if the server is not reachable.
+	// Has no effect for single connections.
+	Lazy bool
+
+	// HealthCheckPeriod determines how often idle connections are checked.
+	// Connections that fail the check are closed
+	// and replaced so that there are at least MinConns connections.
+	// If HealthCheckPeriod is zero, idle connections are not checked.
+	// Has no effect for single connections.
+	HealthCheckPeriod time.Duration
+
+	// MaxConnLifetime is how long a connection may be used.
+	// Connections older than this are closed when they are released
+	// and new connections are made as needed.
+	// A random jitter of up to 10% is added to each connection's lifetime
+	// so that connections made at the same time are not all closed at once.
+	// If MaxConnLifetime is zero, connections are used indefinitely.
+	// Has no effect for single connections.
+	MaxConnLifetime time.Duration
+
+	// MaxConnIdleTime is how long a connection may be idle
+	// before it is closed by the health check.
+	// If MaxConnIdleTime is zero, idle connections are kept open.
+	// Has no effect unless HealthCheckPeriod is set.
+	MaxConnIdleTime time.Duration
+
+	// TraceAcquires records the stack trace of each connection acquisition
+	// so that Pool.Shutdown can report where leaked connections
+	// were acquired. Recording stack traces is slow,
+	// so this should only be used for debugging.
+	TraceAcquires bool
+
 	// ServerSettings is currently unused.
 	ServerSettings map[string]string
+
+	// OnServerMessage is called with log messages sent by the server.
+	// The attributes are the message headers keyed by header code.
+	// It may be called concurrently from multiple goroutines.
+	// If OnServerMessage is nil the messages are written
+	// to the standard logger.
+	OnServerMessage ServerMessageHandler
+
+	// TLSConfig is used to establish TLS connections to the server.
+	// If TLSConfig is set TLSCAFile and TLSSecurity are ignored.
+	// Connections to Unix-domain sockets never use TLS.
+	TLSConfig *tls.Config
+
+	// TLSCAFile is the path to a file containing PEM encoded certificates
+	// used to verify the server certificate.
+	// If not specified, the value parsed from the dsn argument is used,
+	// or the certificate data from the instance credentials file,
+	// or the system root certificates.
+	TLSCAFile string
+
+	// TLSSecurity determines how the server certificate is verified.
+	// If not specified, the value parsed from the dsn argument is used,
+	// or the value derived from the instance credentials file.
+	// Connections are made without TLS
+	// unless TLSConfig, TLSCAFile or TLSSecurity is specified
+	// in the options, the dsn argument or the instance credentials file.
+	TLSSecurity TLSSecurityMode
 }
 
+// TLSSecurityMode documentation can be found here
+// https://www.edgedb.com/docs/reference/connection#ref-reference-connection
+type TLSSecurityMode string
+
+// The available modes are:
+const (
+	// TLSModeDefault uses TLSModeNoHostVerification
+	// if a certificate authority is specified, and TLSModeStrict otherwise.
+	TLSModeDefault TLSSecurityMode = "default"
+
+	// TLSModeStrict verifies the certificate chain and the host name.
+	TLSModeStrict TLSSecurityMode = "strict"
+
+	// TLSModeNoHostVerification verifies the certificate chain
+	// but not the host name.
+	TLSModeNoHostVerification TLSSecurityMode = "no_host_verification"
+
+	// TLSModeInsecure does not verify the server certificate.
+	TLSModeInsecure TLSSecurityMode = "insecure"
+)
+
+// PasswordProvider returns the password for a new connection.
+// ctx is done when the connect timeout expires.
+// It may be called concurrently from multiple goroutines.
+type PasswordProvider func(ctx context.Context) (string, error)
+
+// ServerMessageHandler handles log messages sent by the server.
+// severity is one of DEBUG, INFO, NOTICE or WARNING.
+type ServerMessageHandler func(
+	severity string,
+	code uint32,
+	text string,
+	attrs map[uint16]string,
+)
+
 // RetryBackoff returns the duration to wait after the nth attempt
 // before making the next attempt when retrying a transaction.
 type RetryBackoff func(n int) time.Duration
@@ -185,6 +346,15 @@ type RetryOptions struct {
 	network     RetryRule
 }
 
+// NewRetryOptions returns the default RetryOptions value.
+func NewRetryOptions() RetryOptions {
+	return RetryOptions{
+		fromFactory: true,
+		txConflict:  NewRetryRule(),
+		network:     NewRetryRule(),
+	}
+}
+
 // WithDefault sets the rule for all conditions to rule.
 func (o RetryOptions) WithDefault(rule RetryRule) RetryOptions { // nolint:gocritic,lll
 	if !rule.fromFactory {
@@ -339,6 +509,111 @@ func (p Pool) WithRetryOptions(opts RetryOptions) *Pool { // nolint:gocritic
 	return &p
 }
 
+// Capabilities are the kinds of commands a query is allowed to run.
+type Capabilities uint64
+
+// The available capabilities are:
+const (
+	CapabilityModifications    Capabilities = 0b1
+	CapabilitySessionConfig    Capabilities = 0b10
+	CapabilityDDL              Capabilities = 0b1000
+	CapabilityPersistentConfig Capabilities = 0b10000
+
+	// AllCapabilities allows every kind of command.
+	AllCapabilities Capabilities = 0xffffffffffffffff
+
+	// ReadOnlyCapabilities only allows commands that do not write data,
+	// change the schema or change configuration.
+	ReadOnlyCapabilities = AllCapabilities &^ (CapabilityModifications |
+		CapabilitySessionConfig |
+		CapabilityDDL |
+		CapabilityPersistentConfig)
+)
+
+// WithCapabilities returns a shallow copy of the pool
+// that only allows queries to run commands with the capabilities in c.
+// The server rejects other commands with a DisabledCapabilityError.
+// Transactions are only allowed through RawTx and RetryingTx
+// regardless of c.
+func (p Pool) WithCapabilities(c Capabilities) *Pool { // nolint:gocritic
+	p.queryOpts.disabledCapabilities = uint64(AllCapabilities &^ c)
+	return &p
+}
+
+// WithReadOnly returns a shallow copy of the pool
+// that only allows queries with ReadOnlyCapabilities.
+func (p Pool) WithReadOnly() *Pool { // nolint:gocritic
+	return p.WithCapabilities(ReadOnlyCapabilities)
+}
+
+// WithPrimary returns a shallow copy of the pool
+// that sends all queries to the primary server
+// instead of the read replicas.
+// This can be used to read data that was just written,
+// before it has been replicated.
+func (p Pool) WithPrimary() *Pool { // nolint:gocritic
+	p.usePrimary = true
+	return &p
+}
+
+// WithImplicitLimit returns a shallow copy of the pool
+// that adds an implicit LIMIT clause to the sets returned by queries.
+// A limit of zero means no limit.
+// Pool copies are cheap, so this can also be used to limit a single query.
+func (p Pool) WithImplicitLimit(limit uint64) *Pool { // nolint:gocritic
+	p.queryOpts.implicitLimit = limit
+	return &p
+}
+
+// WithImplicitTypeNames returns a shallow copy of the pool
+// that adds the object type name to every object returned by queries.
+// The name can be decoded into a struct field tagged
+// with `edgedb:"__tname__"`.
+func (p Pool) WithImplicitTypeNames(enabled bool) *Pool { // nolint:gocritic
+	p.queryOpts.implicitTypeNames = enabled
+	return &p
+}
+
+// WithImplicitTypeIDs returns a shallow copy of the pool
+// that adds the object type id to every object returned by queries.
+// The id can be decoded into a UUID struct field tagged
+// with `edgedb:"__tid__"`.
+func (p Pool) WithImplicitTypeIDs(enabled bool) *Pool { // nolint:gocritic
+	p.queryOpts.implicitTypeIDs = enabled
+	return &p
+}
+
+// ModuleAlias maps an alias name to a module name.
+type ModuleAlias struct {
+	Alias  string
+	Module string
+}
+
+// WithGlobals returns a shallow copy of the pool
+// with the global variables in globals set.
+// Global names without a module are in the default module.
+// Session state requires EdgeDB 2.0 or later.
+func (p Pool) WithGlobals(globals map[string]interface{}) *Pool { // nolint:gocritic,lll
+	p.state = withStateValues(p.state, "globals", qualifyGlobals(globals))
+	return &p
+}
+
+// WithConfig returns a shallow copy of the pool
+// with the session config settings in cfg set.
+// Session state requires EdgeDB 2.0 or later.
+func (p Pool) WithConfig(cfg map[string]interface{}) *Pool { // nolint:gocritic
+	p.state = withStateValues(p.state, "config", cfg)
+	return &p
+}
+
+// WithModuleAliases returns a shallow copy of the pool
+// with the module aliases set.
+// Session state requires EdgeDB 2.0 or later.
+func (p Pool) WithModuleAliases(aliases ...ModuleAlias) *Pool { // nolint:gocritic,lll
+	p.state = withModuleAliases(p.state, aliases)
+	return &p
+}
+
 // WithTxOptions returns a shallow copy of the connection
 // with the TxOptions set to opts.
 func (c PoolConn) WithTxOptions(opts TxOptions) *PoolConn { // nolint:gocritic
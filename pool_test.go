@@ -20,6 +20,7 @@ import (
 	"context"
 	"errors"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -75,6 +76,43 @@ func TestPoolRejectsTransaction(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestPoolReadOnly(t *testing.T) {
+	ctx := context.Background()
+	p, err := Connect(ctx, opts)
+	require.Nil(t, err)
+	defer p.Close() // nolint:errcheck
+
+	readOnly := p.WithReadOnly()
+
+	var result int64
+	err = readOnly.QueryOne(ctx, "SELECT 1", &result)
+	require.Nil(t, err)
+	assert.Equal(t, int64(1), result)
+
+	var edbErr Error
+
+	err = readOnly.Execute(ctx, "CREATE TYPE ReadOnlyPoolTest")
+	require.True(t, errors.As(err, &edbErr), err)
+	assert.True(t, edbErr.Category(DisabledCapabilityError), err)
+
+	err = readOnly.Execute(
+		ctx,
+		"CONFIGURE SESSION SET query_work_mem := <cfg::memory>'1MiB'",
+	)
+	require.True(t, errors.As(err, &edbErr), err)
+	assert.True(t, edbErr.Category(DisabledCapabilityError), err)
+
+	err = readOnly.RawTx(ctx, func(ctx context.Context, tx *Tx) error {
+		return tx.Execute(ctx, "CREATE TYPE ReadOnlyPoolTest")
+	})
+	require.True(t, errors.As(err, &edbErr), err)
+	assert.True(t, edbErr.Category(DisabledCapabilityError), err)
+
+	// the original pool is not read only.
+	err = p.Execute(ctx, "CONFIGURE SESSION RESET query_work_mem")
+	assert.Nil(t, err)
+}
+
 func TestConnectPoolZeroMinAndMaxConns(t *testing.T) {
 	o := opts
 	o.MinConns = 0
@@ -84,8 +122,9 @@ func TestConnectPoolZeroMinAndMaxConns(t *testing.T) {
 	p, err := Connect(ctx, o)
 	require.Nil(t, err)
 
-	require.Equal(t, defaultMinConns, p.minConns)
-	require.Equal(t, defaultMaxConns, p.maxConns)
+	minConns, maxConns := p.capacity.size()
+	require.Equal(t, defaultMinConns, minConns)
+	require.Equal(t, defaultMaxConns, maxConns)
 
 	var result string
 	err = p.QueryOne(ctx, "SELECT 'hello';", &result)
@@ -134,14 +173,22 @@ func TestConnectPoolMinConnLteMaxConn(t *testing.T) {
 
 func mockPool(opts Options) *Pool { // nolint:gocritic
 	False := false
+	hosts, err := newHostSet(nil, &opts)
+	if err != nil {
+		panic(err)
+	}
 
 	return &Pool{
-		isClosed:       &False,
-		mu:             &sync.RWMutex{},
-		maxConns:       int(opts.MaxConns),
-		minConns:       int(opts.MinConns),
-		freeConns:      make(chan *reconnectingConn, opts.MinConns),
-		potentialConns: make(chan struct{}, opts.MaxConns),
+		isClosed: &False,
+		mu:       &sync.RWMutex{},
+		capacity: newPoolCapacity(
+			int(opts.MinConns),
+			int(opts.MaxConns),
+		),
+		healthCheckDone: make(chan struct{}),
+		stats:           &poolStats{},
+		acquired:        newAcquiredConns(),
+		hosts:           hosts,
 	}
 }
 
@@ -160,7 +207,7 @@ func TestAcquireFromClosedPool(t *testing.T) {
 func TestAcquireFreeConnFromPool(t *testing.T) {
 	p := mockPool(Options{MinConns: 1})
 	conn := &reconnectingConn{}
-	p.freeConns <- conn
+	p.capacity.freeConns <- conn
 
 	pConn, err := p.Acquire(context.Background())
 	assert.Nil(t, err)
@@ -170,8 +217,8 @@ func TestAcquireFreeConnFromPool(t *testing.T) {
 func BenchmarkPoolAcquireRelease(b *testing.B) {
 	p := mockPool(Options{MaxConns: 2, MinConns: 2})
 
-	for i := 0; i < p.maxConns; i++ {
-		p.freeConns <- &reconnectingConn{}
+	for i := 0; i < p.capacity.maxConns; i++ {
+		p.capacity.freeConns <- &reconnectingConn{}
 	}
 
 	var conn *reconnectingConn
@@ -205,8 +252,8 @@ func TestAcquirePotentialConnFromPool(t *testing.T) {
 
 func TestPoolAcquireExpiredContext(t *testing.T) {
 	p := mockPool(Options{MaxConns: 1, MinConns: 1})
-	p.freeConns <- &reconnectingConn{}
-	p.potentialConns <- struct{}{}
+	p.capacity.freeConns <- &reconnectingConn{}
+	p.capacity.potentialConns <- struct{}{}
 
 	ctx, cancel := context.WithDeadline(context.Background(), time.Now())
 	cancel()
@@ -254,3 +301,55 @@ func TestPoolRetryingTx(t *testing.T) {
 	require.Nil(t, err, "unexpected error: %v", err)
 	require.Equal(t, int64(693), result, "Pool.RetryingTx() failed")
 }
+
+func TestLazyPoolConnectsOnAcquire(t *testing.T) {
+	server := startStallingServer(t)
+	defer server.close()
+
+	o := server.options()
+	o.Lazy = true
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p, err := Connect(ctx, o)
+	require.Nil(t, err)
+	defer p.Close() // nolint:errcheck
+
+	assert.Equal(t, int32(0), atomic.LoadInt32(&server.accepted))
+	assert.Equal(t, 0, p.Stat().TotalConns)
+
+	require.Nil(t, p.Ping(ctx))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&server.accepted))
+	assert.Equal(t, 1, p.Stat().IdleConns)
+}
+
+func TestLazyPoolServerUnavailable(t *testing.T) {
+	server := startStallingServer(t)
+	o := server.options()
+	server.close()
+
+	o.Lazy = true
+	o.WaitUntilAvailable = 50 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p, err := Connect(ctx, o)
+	require.Nil(t, err)
+	defer p.Close() // nolint:errcheck
+
+	start := time.Now()
+	conn, err := p.Acquire(ctx)
+	assert.Nil(t, conn)
+	assert.True(t, time.Since(start) >= o.WaitUntilAvailable)
+
+	var edbErr Error
+	require.True(t, errors.As(err, &edbErr), "wrong error: %v", err)
+	assert.True(t, edbErr.Category(ClientConnectionError), err)
+
+	// the capacity was returned to the pool.
+	assert.Equal(t, 0, p.Stat().TotalConns)
+	_, potentialConns, _ := p.capacity.chans()
+	assert.Equal(t, 1, len(potentialConns))
+}
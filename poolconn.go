@@ -127,6 +127,40 @@ func (c *PoolConn) QueryOneJSON(
 	return err
 }
 
+// QueryIter runs a query and returns an iterator over the results.
+// The connection is not usable until the iterator is closed.
+func (c *PoolConn) QueryIter(
+	ctx context.Context,
+	cmd string,
+	args ...interface{},
+) (*Rows, error) {
+	rows, err := c.conn.QueryIter(ctx, cmd, args...)
+	c.checkErr(err)
+	if err != nil {
+		return nil, err
+	}
+
+	release := rows.release
+	rows.release = func(err error) error {
+		err = release(err)
+		c.checkErr(err)
+		return err
+	}
+
+	return rows, nil
+}
+
+// Describe returns the input and output types of a query
+// without running it.
+func (c *PoolConn) Describe(
+	ctx context.Context,
+	cmd string,
+) (*QueryDescription, error) {
+	description, err := c.conn.Describe(ctx, cmd)
+	c.checkErr(err)
+	return description, err
+}
+
 // RawTx runs an action in a transaction.
 // If the action returns an error the transaction is rolled back,
 // otherwise it is committed.
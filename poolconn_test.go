@@ -26,14 +26,14 @@ import (
 )
 
 func TestReleasePoolConn(t *testing.T) {
-	p := &Pool{freeConns: make(chan *reconnectingConn, 1)}
+	p := mockPool(Options{MinConns: 1, MaxConns: 1})
 	conn := &reconnectingConn{}
 	pConn := &PoolConn{pool: p, conn: conn}
 
 	err := pConn.Release()
 	require.Nil(t, err)
 
-	result := <-p.freeConns
+	result := <-p.capacity.freeConns
 	assert.Equal(t, conn, result)
 
 	err = pConn.Release()
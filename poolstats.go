@@ -0,0 +1,98 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedb
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// PoolStats are statistics about a pool's connections.
+type PoolStats struct {
+	// MaxConns is the maximum number of connections.
+	MaxConns int
+
+	// TotalConns is the number of open connections.
+	TotalConns int
+
+	// IdleConns is the number of open connections
+	// that are waiting to be acquired.
+	IdleConns int
+
+	// AcquiredConns is the number of connections
+	// that are currently acquired.
+	AcquiredConns int
+
+	// WaitingAcquires is the number of goroutines
+	// waiting for a connection to be acquired.
+	WaitingAcquires int
+
+	// AcquireCount is the number of connections that have been acquired.
+	AcquireCount int64
+
+	// AcquireWaitDuration is the total time spent waiting
+	// for connections to be acquired.
+	AcquireWaitDuration time.Duration
+
+	// CreatedConns is the number of connections that have been created.
+	CreatedConns int64
+
+	// ClosedConns is the number of connections that have been closed.
+	ClosedConns int64
+
+	// Reconnects is the number of times connecting to the server
+	// was retried after a temporary failure.
+	Reconnects int64
+
+	// HostConns is the number of connections to each host address.
+	HostConns map[string]int
+}
+
+// poolStats is shared by copies of a pool.
+// It is only accessed atomically.
+type poolStats struct {
+	acquired         int64
+	waiting          int64
+	acquireCount     int64
+	acquireWaitNanos int64
+	created          int64
+	closed           int64
+	reconnects       int64
+}
+
+// Stat returns statistics about the pool's connections.
+func (p *Pool) Stat() PoolStats {
+	created := atomic.LoadInt64(&p.stats.created)
+	closed := atomic.LoadInt64(&p.stats.closed)
+	_, maxConns := p.capacity.size()
+
+	return PoolStats{
+		MaxConns:        maxConns,
+		TotalConns:      int(created - closed),
+		IdleConns:       p.capacity.idle(),
+		AcquiredConns:   int(atomic.LoadInt64(&p.stats.acquired)),
+		WaitingAcquires: int(atomic.LoadInt64(&p.stats.waiting)),
+		AcquireCount:    atomic.LoadInt64(&p.stats.acquireCount),
+		AcquireWaitDuration: time.Duration(
+			atomic.LoadInt64(&p.stats.acquireWaitNanos),
+		),
+		CreatedConns: created,
+		ClosedConns:  closed,
+		Reconnects:   atomic.LoadInt64(&p.stats.reconnects),
+		HostConns:    p.hosts.conns(),
+	}
+}
@@ -0,0 +1,88 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolStat(t *testing.T) {
+	server := startStallingServer(t)
+	defer server.close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p, err := Connect(ctx, server.options())
+	require.Nil(t, err)
+
+	assert.Equal(t, PoolStats{
+		MaxConns:     1,
+		TotalConns:   1,
+		IdleConns:    1,
+		CreatedConns: 1,
+		HostConns:    map[string]int{server.ln.Addr().String(): 1},
+	}, p.Stat())
+
+	conn, err := p.Acquire(ctx)
+	require.Nil(t, err)
+
+	stats := p.Stat()
+	assert.Equal(t, 0, stats.IdleConns)
+	assert.Equal(t, 1, stats.AcquiredConns)
+	assert.Equal(t, int64(1), stats.AcquireCount)
+
+	acquired := make(chan error)
+	go func() {
+		c, e := p.Acquire(ctx)
+		if e == nil {
+			e = c.Release()
+		}
+		acquired <- e
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for p.Stat().WaitingAcquires != 1 {
+		require.True(t, time.Now().Before(deadline), "timed out")
+		time.Sleep(time.Millisecond)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	require.Nil(t, conn.Release())
+	require.Nil(t, <-acquired)
+
+	stats = p.Stat()
+	assert.Equal(t, 0, stats.WaitingAcquires)
+	assert.Equal(t, 0, stats.AcquiredConns)
+	assert.Equal(t, int64(2), stats.AcquireCount)
+	assert.GreaterOrEqual(
+		t,
+		int64(stats.AcquireWaitDuration),
+		int64(10*time.Millisecond),
+	)
+
+	require.Nil(t, p.Close())
+
+	stats = p.Stat()
+	assert.Equal(t, 0, stats.TotalConns)
+	assert.Equal(t, int64(1), stats.ClosedConns)
+}
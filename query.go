@@ -18,9 +18,11 @@ package edgedb
 
 import (
 	"reflect"
+	"strconv"
 
 	"github.com/edgedb/edgedb-go/internal/cardinality"
 	"github.com/edgedb/edgedb-go/internal/format"
+	"github.com/edgedb/edgedb-go/internal/header"
 	"github.com/edgedb/edgedb-go/internal/marshal"
 )
 
@@ -28,10 +30,63 @@ import (
 type sfQuery struct {
 	cmd     string
 	headers msgHeaders
+	state   map[string]interface{}
 }
 
 type msgHeaders map[uint16][]byte
 
+// queryOptions are set on a pool and determine the headers
+// sent with each query.
+type queryOptions struct {
+	// disabledCapabilities are masked off for every query.
+	disabledCapabilities uint64
+
+	implicitLimit     uint64
+	implicitTypeNames bool
+	implicitTypeIDs   bool
+}
+
+// scriptHeaders returns the headers for scripts
+// with the capabilities in disabled masked off.
+func (o queryOptions) scriptHeaders(disabled uint64) msgHeaders {
+	disabled |= o.disabledCapabilities
+
+	switch disabled {
+	case 0:
+		return msgHeaders{}
+	case header.AllowCapabilitieTransaction:
+		return msgHeaders{header.AllowCapabilities: noTxCapabilities}
+	default:
+		return msgHeaders{
+			header.AllowCapabilities: header.NewAllowCapabilitiesWithout(
+				disabled,
+			),
+		}
+	}
+}
+
+// headers returns the headers for granular flow queries
+// with the capabilities in disabled masked off.
+func (o queryOptions) headers(disabled uint64) msgHeaders {
+	headers := o.scriptHeaders(disabled)
+
+	if o.implicitLimit > 0 {
+		headers[header.ImplicitLimit] = []byte(
+			strconv.FormatUint(o.implicitLimit, 10),
+		)
+	}
+
+	if o.implicitTypeNames {
+		headers[header.ImplicitTypeNames] = []byte("true")
+	}
+
+	if o.implicitTypeIDs {
+		headers[header.ImplicitTypeIDs] = []byte("true")
+	}
+
+	return headers
+}
+
 // gfQuery is a granular flow query
 type gfQuery struct {
 	out     reflect.Value
@@ -41,6 +96,7 @@ type gfQuery struct {
 	expCard uint8
 	args    []interface{}
 	headers msgHeaders
+	state   map[string]interface{}
 }
 
 // newQuery returns a new granular flow query.
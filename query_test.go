@@ -175,6 +175,39 @@ func TestNamedQueryArguments(t *testing.T) {
 	assert.Equal(t, [][]int64{{5, 8}}, result)
 }
 
+func TestNamedQueryArgumentsFromStruct(t *testing.T) {
+	ctx := context.Background()
+
+	type Params struct {
+		First  int64 `edgedb:"first"`
+		Second int64 `edgedb:"second"`
+	}
+
+	var result [][]int64
+	err := conn.Query(
+		ctx,
+		"SELECT [<int64>$first, <int64>$second]",
+		&result,
+		Params{First: 5, Second: 8},
+	)
+
+	require.Nil(t, err)
+	assert.Equal(t, [][]int64{{5, 8}}, result)
+
+	err = conn.Query(
+		ctx,
+		"SELECT [<int64>$first, <int64>$second]",
+		&result,
+		map[string]int64{"first": 5},
+	)
+
+	assert.EqualError(
+		t,
+		err,
+		"edgedb.InvalidArgumentError: missing argument args.second",
+	)
+}
+
 func TestNumberedQueryArguments(t *testing.T) {
 	ctx := context.Background()
 	result := [][]int64{}
@@ -299,3 +332,107 @@ func TestNilResultValue(t *testing.T) {
 	assert.EqualError(t, err,
 		"the \"out\" argument must be a pointer, got untyped nil")
 }
+
+func TestImplicitLimit(t *testing.T) {
+	ctx := context.Background()
+	p, err := Connect(ctx, opts)
+	require.Nil(t, err)
+	defer p.Close() // nolint:errcheck
+
+	var result []int64
+	err = p.WithImplicitLimit(2).Query(ctx, "SELECT {1, 2, 3}", &result)
+	require.Nil(t, err)
+	assert.Equal(t, []int64{1, 2}, result)
+
+	err = p.Query(ctx, "SELECT {1, 2, 3}", &result)
+	require.Nil(t, err)
+	assert.Equal(t, []int64{1, 2, 3}, result)
+}
+
+func TestImplicitTypeNamesAndIDs(t *testing.T) {
+	ctx := context.Background()
+	p, err := Connect(ctx, opts)
+	require.Nil(t, err)
+	defer p.Close() // nolint:errcheck
+
+	type Database struct {
+		TypeName string `edgedb:"__tname__"`
+		TypeID   UUID   `edgedb:"__tid__"`
+		Name     string `edgedb:"name"`
+	}
+
+	var result Database
+	err = p.WithImplicitTypeNames(true).WithImplicitTypeIDs(true).QueryOne(
+		ctx, `
+		SELECT sys::Database{ name }
+		FILTER .name = 'edgedb'
+		LIMIT 1`,
+		&result,
+	)
+	require.Nil(t, err)
+	assert.Equal(t, "sys::Database", result.TypeName)
+	assert.NotEqual(t, UUID{}, result.TypeID)
+	assert.Equal(t, "edgedb", result.Name)
+
+	// structs without the implicit fields can still be decoded.
+	type Named struct {
+		Name string `edgedb:"name"`
+	}
+
+	var named Named
+	err = p.WithImplicitTypeNames(true).QueryOne(
+		ctx, `
+		SELECT sys::Database{ name }
+		FILTER .name = 'edgedb'
+		LIMIT 1`,
+		&named,
+	)
+	require.Nil(t, err)
+	assert.Equal(t, "edgedb", named.Name)
+}
+
+func TestImplicitHeadersWithPoolCopies(t *testing.T) {
+	ctx := context.Background()
+	p, err := Connect(ctx, opts)
+	require.Nil(t, err)
+	defer p.Close() // nolint:errcheck
+
+	type Named struct {
+		Name string `edgedb:"name"`
+	}
+
+	type Database struct {
+		TypeName string `edgedb:"__tname__"`
+		Name     string `edgedb:"name"`
+	}
+
+	query := `
+		SELECT sys::Database{ name }
+		FILTER .name = 'edgedb'
+		LIMIT 1`
+
+	// the pool copies share a type id cache,
+	// so the same query is run with alternating headers.
+	for i := 0; i < 2; i++ {
+		var named Named
+		err = p.QueryOne(ctx, query, &named)
+		require.Nil(t, err)
+		assert.Equal(t, "edgedb", named.Name)
+
+		var typed Database
+		err = p.WithImplicitTypeNames(true).QueryOne(ctx, query, &typed)
+		require.Nil(t, err)
+		assert.Equal(t, "sys::Database", typed.TypeName)
+		assert.Equal(t, "edgedb", typed.Name)
+
+		var limited []int64
+		err = p.WithImplicitLimit(2).Query(ctx, "SELECT {1, 2, 3}", &limited)
+		require.Nil(t, err)
+		assert.Equal(t, []int64{1, 2}, limited)
+
+		var all []int64
+		err = p.Query(ctx, "SELECT {1, 2, 3}", &all)
+		require.Nil(t, err)
+		assert.Equal(t, []int64{1, 2, 3}, all)
+	}
+}
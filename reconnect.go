@@ -20,6 +20,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"sync/atomic"
 	"time"
 
 	"github.com/edgedb/edgedb-go/internal/cardinality"
@@ -39,6 +41,39 @@ type reconnectingConn struct {
 	// isClosed is true when the connection has been closed by a user.
 	isClosed bool
 	conn     *baseConn
+
+	// state is the session state sent with each query.
+	state map[string]interface{}
+
+	queryOpts queryOptions
+
+	// idleSince is when the connection was last released to a pool.
+	idleSince time.Time
+
+	// expiresAt is when a pool stops using the connection.
+	// It is zero if the connection does not expire.
+	expiresAt time.Time
+
+	// stats is nil if the connection is not in a pool.
+	stats *poolStats
+
+	// hosts chooses the host to connect to.
+	hosts *hostSet
+
+	// addr is the address the connection is connected to.
+	// It is nil until the connection has been connected.
+	addr *dialArgs
+}
+
+// scriptHeaders returns the headers for scripts
+// run outside of a transaction.
+func (b *reconnectingConn) scriptHeaders() msgHeaders {
+	return b.queryOpts.scriptHeaders(header.AllowCapabilitieTransaction)
+}
+
+// headers returns the headers for queries run outside of a transaction.
+func (b *reconnectingConn) headers() msgHeaders {
+	return b.queryOpts.headers(header.AllowCapabilitieTransaction)
 }
 
 func (b *reconnectingConn) assertUnborrowed() error {
@@ -48,6 +83,11 @@ func (b *reconnectingConn) assertUnborrowed() error {
 			msg: "Connection is borrowed for a transaction. " +
 				"Use the methods on transaction object instead.",
 		}
+	case "iterator":
+		return &interfaceError{
+			msg: "Connection is borrowed for a query iterator. " +
+				"Close the iterator before using the connection.",
+		}
 	case "":
 		return nil
 	default:
@@ -61,7 +101,7 @@ func (b *reconnectingConn) borrow(reason string) error {
 		return &interfaceError{msg: msg}
 	}
 
-	if reason != "transaction" {
+	if reason != "transaction" && reason != "iterator" {
 		panic(fmt.Sprintf("unexpected reason: %q", reason))
 	}
 
@@ -93,25 +133,72 @@ func (b *reconnectingConn) reconnect(ctx context.Context) (err error) {
 	var edbErr Error
 
 	for i := 1; true; i++ {
-		for _, addr := range b.conn.cfg.addrs {
+		addrs, wait := b.hosts.order()
+		if len(addrs) == 0 {
+			return &configurationError{
+				msg: "HostSelector did not return any known address",
+			}
+		}
+
+		// Every host is down, so wait for the first one to come back up
+		// but not past the time that connecting is given up on.
+		if wait > 0 {
+			if time.Now().Add(wait).After(maxTime) {
+				wait = time.Until(maxTime)
+			}
+
+			if e := sleep(ctx, wait); e != nil {
+				return firstError(err, e)
+			}
+		}
+
+		shouldReconnect := false
+		for _, addr := range addrs {
 			err = connectWithTimeout(ctx, b.conn, addr)
-			if err == nil ||
-				errors.Is(err, context.Canceled) ||
+			if err == nil {
+				b.hosts.connected(b.addr, addr)
+				b.addr = addr
+				return nil
+			}
+
+			if errors.Is(err, context.Canceled) ||
 				errors.Is(err, context.DeadlineExceeded) ||
 				!errors.As(err, &edbErr) ||
-				!edbErr.Category(ClientConnectionError) ||
-				!edbErr.HasTag("SHOULD_RECONNECT") ||
-				(i > 1 && time.Now().After(maxTime)) {
+				!edbErr.Category(ClientConnectionError) {
 				return err
 			}
+
+			b.hosts.failed(addr)
+			if edbErr.HasTag("SHOULD_RECONNECT") {
+				shouldReconnect = true
+			}
+
+			if b.stats != nil {
+				atomic.AddInt64(&b.stats.reconnects, 1)
+			}
 		}
 
-		time.Sleep(time.Duration(10+rnd.Intn(200)) * time.Millisecond)
+		if !shouldReconnect || (i > 1 && time.Now().After(maxTime)) {
+			return err
+		}
 	}
 
 	panic("unreachable")
 }
 
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("edgedb: %w", ctx.Err())
+	}
+}
+
 // ensureConnection reconnects to the server if not connected.
 func (b *reconnectingConn) ensureConnection(ctx context.Context) error {
 	if b.conn != nil && !b.isClosed {
@@ -130,12 +217,13 @@ func (b *reconnectingConn) scriptFlow(ctx context.Context, q sfQuery) error {
 		return e
 	}
 
+	q.state = b.state
 	return b.conn.ScriptFlow(ctx, q)
 }
 
 // Execute an EdgeQL command (or commands).
 func (b *reconnectingConn) Execute(ctx context.Context, cmd string) error {
-	hdrs := msgHeaders{header.AllowCapabilities: noTxCapabilities}
+	hdrs := b.scriptHeaders()
 	return b.scriptFlow(ctx, sfQuery{cmd: cmd, headers: hdrs})
 }
 
@@ -151,6 +239,7 @@ func (b *reconnectingConn) granularFlow(
 		return e
 	}
 
+	q.state = b.state
 	return b.conn.GranularFlow(ctx, q)
 }
 
@@ -161,7 +250,7 @@ func (b *reconnectingConn) Query(
 	out interface{},
 	args ...interface{},
 ) error {
-	hdrs := msgHeaders{header.AllowCapabilities: noTxCapabilities}
+	hdrs := b.headers()
 	q, err := newQuery(cmd, format.Binary, cardinality.Many, args, hdrs, out)
 	if err != nil {
 		return err
@@ -179,7 +268,7 @@ func (b *reconnectingConn) QueryOne(
 	out interface{},
 	args ...interface{},
 ) error {
-	hdrs := msgHeaders{header.AllowCapabilities: noTxCapabilities}
+	hdrs := b.headers()
 	q, err := newQuery(cmd, format.Binary, cardinality.One, args, hdrs, out)
 	if err != nil {
 		return err
@@ -195,7 +284,7 @@ func (b *reconnectingConn) QueryJSON(
 	out *[]byte,
 	args ...interface{},
 ) error {
-	hdrs := msgHeaders{header.AllowCapabilities: noTxCapabilities}
+	hdrs := b.headers()
 	q, err := newQuery(cmd, format.JSON, cardinality.Many, args, hdrs, out)
 	if err != nil {
 		return err
@@ -213,7 +302,7 @@ func (b *reconnectingConn) QueryOneJSON(
 	out *[]byte,
 	args ...interface{},
 ) error {
-	hdrs := msgHeaders{header.AllowCapabilities: noTxCapabilities}
+	hdrs := b.headers()
 	q, err := newQuery(cmd, format.JSON, cardinality.One, args, hdrs, out)
 	if err != nil {
 		return err
@@ -222,6 +311,89 @@ func (b *reconnectingConn) QueryOneJSON(
 	return b.granularFlow(ctx, q)
 }
 
+func (b *reconnectingConn) dump(ctx context.Context, out io.Writer) error {
+	if e := b.assertUnborrowed(); e != nil {
+		return e
+	}
+
+	if e := b.ensureConnection(ctx); e != nil {
+		return e
+	}
+
+	return b.conn.Dump(ctx, out)
+}
+
+func (b *reconnectingConn) restore(ctx context.Context, in io.Reader) error {
+	if e := b.assertUnborrowed(); e != nil {
+		return e
+	}
+
+	if e := b.ensureConnection(ctx); e != nil {
+		return e
+	}
+
+	return b.conn.Restore(ctx, in)
+}
+
+// QueryIter runs a query and returns an iterator over the results.
+// The connection is not usable until the iterator is closed.
+func (b *reconnectingConn) QueryIter(
+	ctx context.Context,
+	cmd string,
+	args ...interface{},
+) (*Rows, error) {
+	if e := b.assertUnborrowed(); e != nil {
+		return nil, e
+	}
+
+	if e := b.ensureConnection(ctx); e != nil {
+		return nil, e
+	}
+
+	hdrs := b.headers()
+	q := newIterQuery(cmd, args, hdrs)
+	q.state = b.state
+
+	if e := b.borrow("iterator"); e != nil {
+		return nil, e
+	}
+
+	rows, err := b.conn.QueryIter(ctx, q)
+	if err != nil {
+		b.unborrow()
+		return nil, err
+	}
+
+	release := rows.release
+	rows.release = func(err error) error {
+		b.unborrow()
+		return release(err)
+	}
+
+	return rows, nil
+}
+
+// Describe returns the input and output types of a query
+// without running it.
+func (b *reconnectingConn) Describe(
+	ctx context.Context,
+	cmd string,
+) (*QueryDescription, error) {
+	if e := b.assertUnborrowed(); e != nil {
+		return nil, e
+	}
+
+	if e := b.ensureConnection(ctx); e != nil {
+		return nil, e
+	}
+
+	hdrs := b.headers()
+	q := newDescribeQuery(cmd, hdrs)
+	q.state = b.state
+
+	return b.conn.Describe(ctx, q)
+}
+
 func (b *reconnectingConn) rawTx(
 	ctx context.Context,
 	action Action,
@@ -236,7 +408,12 @@ func (b *reconnectingConn) rawTx(
 		return e
 	}
 
-	tx := &Tx{conn: b.conn, options: options}
+	tx := &Tx{
+		conn:         b.conn,
+		options:      options,
+		sessionState: b.state,
+		queryOpts:    b.queryOpts,
+	}
 	if e := tx.start(ctx); e != nil {
 		return e
 	}
@@ -266,7 +443,12 @@ func (b *reconnectingConn) retryingTx(
 			return e
 		}
 
-		tx := &Tx{conn: b.conn, options: txOpts}
+		tx := &Tx{
+			conn:         b.conn,
+			options:      txOpts,
+			sessionState: b.state,
+			queryOpts:    b.queryOpts,
+		}
 		if e := tx.start(ctx); e != nil {
 			return e
 		}
@@ -299,5 +481,10 @@ func (b *reconnectingConn) retryingTx(
 
 func (b *reconnectingConn) close() error {
 	b.isClosed = true
+	if b.addr != nil {
+		b.hosts.closed(b.addr)
+		b.addr = nil
+	}
+
 	return b.conn.close()
 }
@@ -45,3 +45,19 @@ func TestRecconnectingConnBorrow(t *testing.T) {
 	err = b.assertUnborrowed()
 	require.Nil(t, err, err)
 }
+
+func TestRecconnectingConnBorrowIterator(t *testing.T) {
+	b := reconnectingConn{}
+	err := b.borrow("iterator")
+	require.Nil(t, err, "unexpected err: %v", err)
+
+	err = b.assertUnborrowed()
+	expected := "edgedb.InterfaceError: " +
+		"Connection is borrowed for a query iterator. " +
+		"Close the iterator before using the connection."
+	require.EqualError(t, err, expected)
+
+	b.unborrow()
+	err = b.assertUnborrowed()
+	require.Nil(t, err, err)
+}
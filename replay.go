@@ -0,0 +1,263 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedb
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/edgedb/edgedb-go/internal/buff"
+	"github.com/edgedb/edgedb-go/internal/message"
+)
+
+// transcriptEvent is a recorded message or the end of a connection.
+type transcriptEvent struct {
+	direction string
+	msg       []byte
+}
+
+// ReplayDialer returns a DialFunc that replays the connections
+// recorded in a transcript written to Options.Transcript.
+// Each dial returns the next recorded connection, regardless of address.
+// The server's messages are sent as the client sends
+// the messages that preceded them in the transcript.
+// Only the types of the client's messages are compared to the transcript.
+// Once the client sends a message of a different type
+// its commands fail with a ProtocolError.
+//
+// Sessions that authenticated with a password can not be replayed
+// because the authentication messages are different each time.
+func ReplayDialer(transcript io.Reader) (DialFunc, error) {
+	conns, err := parseTranscript(transcript)
+	if err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+
+	return func(
+		ctx context.Context,
+		network string,
+		address string,
+	) (net.Conn, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if len(conns) == 0 {
+			return nil, &clientConnectionFailedError{
+				msg: "no more connections in the transcript",
+			}
+		}
+
+		events := conns[0]
+		conns = conns[1:]
+
+		client, server := net.Pipe()
+		go replay(server, events)
+
+		return client, nil
+	}, nil
+}
+
+// parseTranscript returns the events of each connection
+// in the order the connections were opened.
+func parseTranscript(transcript io.Reader) ([][]transcriptEvent, error) {
+	var (
+		order  []string
+		events = make(map[string][]transcriptEvent)
+	)
+
+	scanner := bufio.NewScanner(transcript)
+	scanner.Buffer(nil, 64*1024*1024)
+
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, invalidTranscript(lineNo, "too few fields")
+		}
+
+		id := fields[0]
+		switch fields[1] {
+		case transcriptOpen:
+			if _, ok := events[id]; ok {
+				return nil, invalidTranscript(lineNo, "connection reopened")
+			}
+
+			order = append(order, id)
+			events[id] = []transcriptEvent{}
+		case transcriptClose:
+			events[id] = append(events[id], transcriptEvent{
+				direction: transcriptClose,
+			})
+		case transcriptClient, transcriptServer:
+			if _, ok := events[id]; !ok {
+				return nil, invalidTranscript(lineNo, "connection not open")
+			}
+
+			if len(fields) != 3 {
+				return nil, invalidTranscript(lineNo, "expected a message")
+			}
+
+			msg, err := hex.DecodeString(fields[2])
+			if err != nil || len(msg) < 5 ||
+				1+int(binary.BigEndian.Uint32(msg[1:5])) != len(msg) {
+				return nil, invalidTranscript(lineNo, "malformed message")
+			}
+
+			events[id] = append(events[id], transcriptEvent{
+				direction: fields[1],
+				msg:       msg,
+			})
+		default:
+			return nil, invalidTranscript(lineNo, fmt.Sprintf(
+				"unknown event %q", fields[1],
+			))
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, &interfaceError{err: err}
+	}
+
+	conns := make([][]transcriptEvent, len(order))
+	for i, id := range order {
+		conns[i] = events[id]
+	}
+
+	return conns, nil
+}
+
+func invalidTranscript(lineNo int, msg string) error {
+	return &interfaceError{msg: fmt.Sprintf(
+		"invalid transcript on line %v: %v", lineNo, msg,
+	)}
+}
+
+// replay plays the server side of a recorded connection.
+func replay(conn net.Conn, events []transcriptEvent) {
+	defer conn.Close() // nolint:errcheck
+
+	r := bufio.NewReader(conn)
+	var pending []byte
+
+	for _, event := range events {
+		if event.direction == transcriptServer {
+			pending = append(pending, event.msg...)
+			continue
+		}
+
+		// server messages are sent together
+		// like the server sent them before the client replied.
+		if len(pending) > 0 {
+			if _, err := conn.Write(pending); err != nil {
+				return
+			}
+			pending = nil
+		}
+
+		if event.direction == transcriptClose {
+			return
+		}
+
+		mType, err := readMessageType(r)
+		if err != nil {
+			return
+		}
+
+		if mType != event.msg[0] {
+			replayMismatch(conn, r, mType, event.msg[0])
+			return
+		}
+	}
+
+	if len(pending) > 0 {
+		_, _ = conn.Write(pending)
+	}
+
+	// wait for the client to close the connection.
+	_, _ = io.Copy(ioutil.Discard, r)
+}
+
+// readMessageType reads a message and returns its type.
+func readMessageType(r *bufio.Reader) (uint8, error) {
+	head := make([]byte, 5)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return 0, err
+	}
+
+	n := int64(binary.BigEndian.Uint32(head[1:])) - 4
+	if _, err := io.CopyN(ioutil.Discard, r, n); err != nil {
+		return 0, err
+	}
+
+	return head[0], nil
+}
+
+// replayMismatch answers the client with a protocol error
+// describing a client message that does not match the transcript.
+// The error is repeated for each following command
+// until the client closes the connection.
+func replayMismatch(conn net.Conn, r *bufio.Reader, got, expected uint8) {
+	msg := fmt.Sprintf(
+		"replay failed: the client sent message type 0x%x "+
+			"but the transcript expected 0x%x",
+		got, expected,
+	)
+
+	for mType := got; ; {
+		switch mType {
+		case message.Sync, message.ExecuteScript:
+			w := buff.NewWriter(nil)
+			w.BeginMessage(message.ErrorResponse)
+			w.PushUint8(0x78)           // severity ERROR
+			w.PushUint32(0x03_00_00_00) // ProtocolError
+			w.PushString(msg)
+			w.PushUint16(0) // no attributes
+			w.EndMessage()
+
+			w.BeginMessage(message.ReadyForCommand)
+			w.PushUint16(0) // no headers
+			w.PushUint8('I')
+			w.EndMessage()
+
+			if w.Send(conn) != nil {
+				return
+			}
+		case message.Terminate:
+			return
+		}
+
+		var err error
+		mType, err = readMessageType(r)
+		if err != nil {
+			return
+		}
+	}
+}
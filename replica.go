@@ -0,0 +1,182 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedb
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// replicaSet is a pool's read replicas.
+// It is shared by copies of a pool.
+type replicaSet struct {
+	mu     sync.Mutex
+	pools  []*Pool
+	states []hostState
+
+	// next is the index of the next replica to use.
+	next int
+}
+
+// connectReplicas creates a lazy pool for each of opts.ReadReplicas.
+func connectReplicas(
+	ctx context.Context,
+	opts Options, // nolint:gocritic
+) (*replicaSet, error) {
+	replicas := opts.ReadReplicas
+	opts.ReadReplicas = nil
+	opts.Lazy = true
+
+	// replicas are not waited for,
+	// queries fall back to the primary instead.
+	opts.WaitUntilAvailable = time.Nanosecond
+
+	s := &replicaSet{
+		pools:  make([]*Pool, 0, len(replicas)),
+		states: make([]hostState, len(replicas)),
+	}
+
+	for _, replica := range replicas {
+		o := opts
+		dsn := ""
+
+		if strings.Contains(replica, "://") {
+			dsn = replica
+			o.Hosts = nil
+			o.Ports = nil
+			o.User = ""
+			o.Password = ""
+			o.Database = ""
+		} else {
+			hosts, ports, err := parseHostList(replica, nil)
+			if err != nil {
+				_ = s.shutdown(ctx)
+				return nil, err
+			}
+
+			o.Hosts = hosts
+			o.Ports = ports
+		}
+
+		p, err := ConnectDSN(ctx, dsn, o)
+		if err != nil {
+			_ = s.shutdown(ctx)
+			return nil, err
+		}
+
+		s.pools = append(s.pools, p)
+	}
+
+	return s, nil
+}
+
+// choose returns the next replica that is not marked down
+// or nil if all of the replicas are down.
+func (s *replicaSet) choose() (*Pool, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for n := 0; n < len(s.pools); n++ {
+		i := s.next
+		s.next = (s.next + 1) % len(s.pools)
+
+		if !s.states[i].isDown() {
+			return s.pools[i], i
+		}
+	}
+
+	return nil, -1
+}
+
+// fallback records the result of using replica i
+// and returns true if the primary should be used instead.
+func (s *replicaSet) fallback(i int, err error) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var edbErr Error
+	switch {
+	case err == nil:
+		s.states[i].markUp()
+		return false
+	case !errors.As(err, &edbErr):
+		return false
+	case edbErr.Category(ClientConnectionError):
+		s.states[i].markDown()
+		return true
+	case edbErr.Category(DisabledCapabilityError):
+		// the query writes data.
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *replicaSet) shutdown(ctx context.Context) error {
+	errs := make([]error, len(s.pools))
+	for i, p := range s.pools {
+		errs[i] = p.Shutdown(ctx)
+	}
+
+	return wrapAll(errs...)
+}
+
+// readReplica returns a copy of a read replica's pool
+// with the options of p or nil if a replica should not be used.
+func (p *Pool) readReplica() (*Pool, int) {
+	if p.replicas == nil || p.usePrimary {
+		return nil, -1
+	}
+
+	replica, i := p.replicas.choose()
+	if replica == nil {
+		return nil, -1
+	}
+
+	r := *replica
+	r.txOpts = p.txOpts
+	r.retryOpts = p.retryOpts
+	r.state = p.state
+	r.queryOpts = p.queryOpts
+	r.queryOpts.disabledCapabilities |= uint64(
+		AllCapabilities &^ ReadOnlyCapabilities,
+	)
+
+	return &r, i
+}
+
+// acquireTx acquires a connection for a transaction
+// from the pool that should run it.
+// Read only transactions use a read replica if there is one.
+func (p *Pool) acquireTx(
+	ctx context.Context,
+) (*Pool, *reconnectingConn, error) {
+	if p.txOpts.readOnly {
+		if r, i := p.readReplica(); r != nil {
+			conn, err := r.acquire(ctx)
+			if !p.replicas.fallback(i, err) {
+				return r, conn, err
+			}
+		}
+	}
+
+	conn, err := p.acquire(ctx)
+	return p, conn, err
+}
@@ -0,0 +1,146 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readOnlyTx(ctx context.Context, tx *Tx) error {
+	return tx.Execute(ctx, "SELECT 1")
+}
+
+func TestReadOnlyTxUsesReplica(t *testing.T) {
+	primary := startStallingServer(t)
+	defer primary.close()
+	replica := startStallingServer(t)
+	defer replica.close()
+
+	o := primary.options()
+	o.ReadReplicas = []string{replica.ln.Addr().String()}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p, err := Connect(ctx, o)
+	require.Nil(t, err)
+	defer p.Close() // nolint:errcheck
+
+	// replicas are connected lazily.
+	assert.Equal(t, int32(0), atomic.LoadInt32(&replica.accepted))
+
+	readOnly := p.WithTxOptions(NewTxOptions().WithReadOnly(true))
+	require.Nil(t, readOnly.RawTx(ctx, readOnlyTx))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&replica.accepted))
+
+	// the primary's connection is still idle.
+	assert.Equal(t, 1, p.Stat().IdleConns)
+	assert.Equal(t, int64(0), p.Stat().AcquireCount)
+
+	require.Nil(t, readOnly.WithPrimary().RawTx(ctx, readOnlyTx))
+	assert.Equal(t, int64(1), p.Stat().AcquireCount)
+
+	// write transactions use the primary.
+	require.Nil(t, p.RawTx(ctx, readOnlyTx))
+	assert.Equal(t, int64(2), p.Stat().AcquireCount)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&replica.accepted))
+}
+
+func TestReadOnlyTxFallsBackToPrimary(t *testing.T) {
+	primary := startStallingServer(t)
+	defer primary.close()
+
+	down, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	require.Nil(t, down.Close())
+
+	o := primary.options()
+	o.ReadReplicas = []string{
+		fmt.Sprintf("edgedb://edgedb@%v/edgedb", down.Addr()),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p, err := Connect(ctx, o)
+	require.Nil(t, err)
+	defer p.Close() // nolint:errcheck
+
+	readOnly := p.WithTxOptions(NewTxOptions().WithReadOnly(true))
+	require.Nil(t, readOnly.RawTx(ctx, readOnlyTx))
+	assert.Equal(t, int64(1), p.Stat().AcquireCount)
+
+	// the replica is marked down.
+	replica, _ := p.replicas.choose()
+	assert.Nil(t, replica)
+
+	require.Nil(t, readOnly.RawTx(ctx, readOnlyTx))
+	assert.Equal(t, int64(2), p.Stat().AcquireCount)
+}
+
+func TestReplicaFallback(t *testing.T) {
+	s := &replicaSet{
+		pools:  []*Pool{{}, {}},
+		states: make([]hostState, 2),
+	}
+
+	replica, i := s.choose()
+	assert.Equal(t, s.pools[0], replica)
+	assert.Equal(t, 0, i)
+
+	assert.False(t, s.fallback(i, nil))
+	assert.False(t, s.fallback(i, &queryError{msg: "bad query"}))
+	assert.False(t, s.fallback(i, errors.New("something else")))
+	assert.True(t, s.fallback(i, &disabledCapabilityError{msg: "write"}))
+	assert.False(t, s.states[0].isDown())
+
+	assert.True(t, s.fallback(i, &clientConnectionFailedError{msg: "down"}))
+	assert.True(t, s.states[0].isDown())
+
+	// replica 0 is skipped while it is down.
+	_, i = s.choose()
+	assert.Equal(t, 1, i)
+	_, i = s.choose()
+	assert.Equal(t, 1, i)
+
+	assert.True(t, s.fallback(i, &clientConnectionFailedError{msg: "down"}))
+	replica, i = s.choose()
+	assert.Nil(t, replica)
+	assert.Equal(t, -1, i)
+}
+
+func TestInvalidReadReplica(t *testing.T) {
+	server := startStallingServer(t)
+	defer server.close()
+
+	o := server.options()
+	o.ReadReplicas = []string{"localhost:notaport"}
+
+	_, err := Connect(context.Background(), o)
+	var edbErr Error
+	require.True(t, errors.As(err, &edbErr), "wrong error: %v", err)
+	assert.True(t, edbErr.Category(ConfigurationError), err)
+}
@@ -0,0 +1,273 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedb
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// poolCapacity holds a pool's idle connections and unconnected capacity.
+// It is shared by copies of a pool so that resizing affects all copies.
+//
+// Values are only sent on the channels while holding mu,
+// so that the channels can be replaced when the pool is resized.
+// Receivers get the current channels from chans
+// and must start over when changed is closed.
+type poolCapacity struct {
+	mu sync.Mutex
+
+	minConns int
+	maxConns int
+
+	// A buffered channel of connections ready for use.
+	freeConns chan *reconnectingConn
+
+	// A buffered channel of structs representing unconnected capacity.
+	potentialConns chan struct{}
+
+	// changed is closed and replaced
+	// when the channels are replaced or surplus is reduced.
+	changed chan struct{}
+
+	// surplus is the number of acquired connections
+	// that must be closed when they are released
+	// because the pool was shrunk.
+	surplus int
+}
+
+func newPoolCapacity(minConns, maxConns int) *poolCapacity {
+	return &poolCapacity{
+		minConns:       minConns,
+		maxConns:       maxConns,
+		freeConns:      make(chan *reconnectingConn, minConns),
+		potentialConns: make(chan struct{}, maxConns),
+		changed:        make(chan struct{}),
+	}
+}
+
+// chans returns the current channels.
+func (c *poolCapacity) chans() (
+	chan *reconnectingConn,
+	chan struct{},
+	chan struct{},
+) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.freeConns, c.potentialConns, c.changed
+}
+
+// size returns the current minimum and maximum number of connections.
+func (c *poolCapacity) size() (minConns, maxConns int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.minConns, c.maxConns
+}
+
+// idle returns the number of idle connections.
+func (c *poolCapacity) idle() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.freeConns)
+}
+
+// notify wakes goroutines that are waiting on the channels.
+// c.mu must be held.
+func (c *poolCapacity) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// wake wakes goroutines that are waiting on the channels
+// so that they check if the pool was closed.
+func (c *poolCapacity) wake() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.notify()
+}
+
+// putConn returns an idle connection.
+// If there is no room for conn, its capacity is returned instead
+// and false is returned to indicate that conn must be closed.
+func (c *poolCapacity) putConn(conn *reconnectingConn) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.surplus > 0 {
+		c.surplus--
+		c.notify()
+		return false
+	}
+
+	select {
+	case c.freeConns <- conn:
+		return true
+	default:
+		c.potentialConns <- struct{}{}
+		return false
+	}
+}
+
+// putToken returns the capacity of a connection that was closed
+// or could not be connected.
+func (c *poolCapacity) putToken() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.surplus > 0 {
+		c.surplus--
+		c.notify()
+		return
+	}
+
+	c.potentialConns <- struct{}{}
+}
+
+// outstanding returns the number of connections and unconnected capacity
+// that exist, minus the drained ones that have been taken out of the pool.
+func (c *poolCapacity) outstanding(drained int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.maxConns + c.surplus - drained
+}
+
+// resize replaces the channels with ones for the new size
+// and returns the idle connections that must be closed.
+func (c *poolCapacity) resize(
+	minConns int,
+	maxConns int,
+) []*reconnectingConn {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	freeConns := make(chan *reconnectingConn, minConns)
+	potentialConns := make(chan struct{}, maxConns)
+
+	var closing []*reconnectingConn
+	tokens := 0
+
+	// waiters may receive from the old channels concurrently,
+	// so they are drained without blocking.
+	for drained := false; !drained; {
+		select {
+		case conn := <-c.freeConns:
+			select {
+			case freeConns <- conn:
+			default:
+				closing = append(closing, conn)
+				tokens++
+			}
+		default:
+			drained = true
+		}
+	}
+
+	for drained := false; !drained; {
+		select {
+		case <-c.potentialConns:
+			tokens++
+		default:
+			drained = true
+		}
+	}
+
+	// excess is the number of connections
+	// that exist beyond the new maximum.
+	excess := c.maxConns + c.surplus - maxConns
+
+	// unconnected capacity is removed first,
+	// then idle connections.
+	if excess > 0 {
+		removed := min(excess, tokens)
+		tokens -= removed
+		excess -= removed
+	}
+
+	for drained := false; excess > 0 && !drained; {
+		select {
+		case conn := <-freeConns:
+			closing = append(closing, conn)
+			excess--
+		default:
+			drained = true
+		}
+	}
+
+	if excess < 0 {
+		tokens -= excess
+		excess = 0
+	}
+
+	for i := 0; i < tokens; i++ {
+		potentialConns <- struct{}{}
+	}
+
+	c.minConns = minConns
+	c.maxConns = maxConns
+	c.freeConns = freeConns
+	c.potentialConns = potentialConns
+	c.surplus = excess
+	c.notify()
+
+	return closing
+}
+
+// Resize changes the minimum and maximum number of connections.
+// Idle connections that no longer fit in the pool are closed.
+// If more connections are acquired than maxConns allows,
+// they are closed as they are released.
+// Goroutines waiting to acquire a connection are woken
+// if the pool is grown.
+// New idle connections are connected by the health check
+// or as they are needed.
+func (p *Pool) Resize(minConns, maxConns int) error {
+	if minConns < 0 || maxConns < 1 {
+		return &configurationError{msg: fmt.Sprintf(
+			"invalid pool size: MinConns (%v), MaxConns (%v)",
+			minConns, maxConns,
+		)}
+	}
+
+	if maxConns < minConns {
+		return &configurationError{msg: fmt.Sprintf(
+			"MaxConns (%v) may not be less than MinConns (%v)",
+			maxConns, minConns,
+		)}
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if *p.isClosed {
+		return &interfaceError{msg: "pool closed"}
+	}
+
+	closing := p.capacity.resize(minConns, maxConns)
+	errs := make([]error, len(closing))
+	for i, conn := range closing {
+		atomic.AddInt64(&p.stats.closed, 1)
+		errs[i] = conn.close()
+	}
+
+	return wrapAll(errs...)
+}
@@ -0,0 +1,161 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolCapacityResize(t *testing.T) {
+	c := newPoolCapacity(1, 3)
+	idle := &reconnectingConn{}
+	require.True(t, c.putConn(idle))
+	c.putToken()
+
+	// one connection is acquired.
+	closing := c.resize(0, 1)
+	assert.Equal(t, []*reconnectingConn{idle}, closing)
+	assert.Equal(t, 0, len(c.freeConns))
+	assert.Equal(t, 0, len(c.potentialConns))
+	assert.Equal(t, 0, c.surplus)
+
+	closing = c.resize(0, 3)
+	assert.Nil(t, closing)
+	assert.Equal(t, 2, len(c.potentialConns))
+
+	// two connections are acquired.
+	<-c.potentialConns
+	closing = c.resize(0, 1)
+	assert.Nil(t, closing)
+	assert.Equal(t, 0, len(c.potentialConns))
+	assert.Equal(t, 1, c.surplus)
+
+	// the surplus connection is closed when it is released.
+	assert.False(t, c.putConn(&reconnectingConn{}))
+	assert.Equal(t, 0, c.surplus)
+	assert.Equal(t, 0, len(c.potentialConns))
+
+	c.putToken()
+	assert.Equal(t, 1, len(c.potentialConns))
+}
+
+func TestPoolResizeWakesWaiters(t *testing.T) {
+	server := startStallingServer(t)
+	defer server.close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p, err := Connect(ctx, server.options())
+	require.Nil(t, err)
+	defer p.Close() // nolint:errcheck
+
+	a, err := p.Acquire(ctx)
+	require.Nil(t, err)
+
+	acquired := make(chan *PoolConn)
+	go func() {
+		b, e := p.Acquire(ctx)
+		assert.Nil(t, e)
+		acquired <- b
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	require.Equal(t, 1, p.Stat().WaitingAcquires)
+	require.Nil(t, p.Resize(1, 2))
+
+	b := <-acquired
+	require.NotNil(t, b)
+	assert.Equal(t, 2, p.Stat().TotalConns)
+	assert.Equal(t, 2, p.Stat().MaxConns)
+
+	require.Nil(t, a.Release())
+	require.Nil(t, b.Release())
+	assert.Equal(t, 1, p.Stat().TotalConns)
+	assert.Equal(t, 1, p.Stat().IdleConns)
+
+	require.Nil(t, p.Resize(0, 1))
+	assert.Equal(t, 0, p.Stat().TotalConns)
+}
+
+func TestPoolResizeClosesSurplusConns(t *testing.T) {
+	server := startStallingServer(t)
+	defer server.close()
+
+	o := server.options()
+	o.MaxConns = 2
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p, err := Connect(ctx, o)
+	require.Nil(t, err)
+	defer p.Close() // nolint:errcheck
+
+	a, err := p.Acquire(ctx)
+	require.Nil(t, err)
+	b, err := p.Acquire(ctx)
+	require.Nil(t, err)
+
+	require.Nil(t, p.Resize(1, 1))
+	require.Nil(t, a.Release())
+	assert.Equal(t, 1, p.Stat().TotalConns)
+	require.Nil(t, b.Release())
+	assert.Equal(t, 1, p.Stat().TotalConns)
+	assert.Equal(t, 1, p.Stat().IdleConns)
+
+	c, err := p.Acquire(ctx)
+	require.Nil(t, err)
+	defer c.Release() // nolint:errcheck
+
+	timeoutCtx, timeoutCancel := context.WithTimeout(
+		ctx,
+		10*time.Millisecond,
+	)
+	defer timeoutCancel()
+
+	_, err = p.Acquire(timeoutCtx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), err)
+}
+
+func TestPoolResizeInvalidSize(t *testing.T) {
+	p := mockPool(Options{MinConns: 1, MaxConns: 1})
+	p.capacity.putToken()
+
+	err := p.Resize(2, 1)
+	assert.EqualError(
+		t,
+		err,
+		"edgedb.ConfigurationError: "+
+			"MaxConns (1) may not be less than MinConns (2)",
+	)
+
+	err = p.Resize(0, 0)
+	var edbErr Error
+	require.True(t, errors.As(err, &edbErr), "wrong error: %v", err)
+	assert.True(t, edbErr.Category(ConfigurationError), err)
+
+	require.Nil(t, p.Close())
+	err = p.Resize(1, 1)
+	assert.EqualError(t, err, "edgedb.InterfaceError: pool closed")
+}
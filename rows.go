@@ -0,0 +1,352 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedb
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"unsafe"
+
+	"github.com/edgedb/edgedb-go/internal/buff"
+	"github.com/edgedb/edgedb-go/internal/cardinality"
+	"github.com/edgedb/edgedb-go/internal/codecs"
+	"github.com/edgedb/edgedb-go/internal/descriptor"
+	"github.com/edgedb/edgedb-go/internal/format"
+	"github.com/edgedb/edgedb-go/internal/marshal"
+	"github.com/edgedb/edgedb-go/internal/message"
+)
+
+// Rows is an iterator over the results of a query.
+// Results are read from the connection and decoded one at a time
+// as the iterator is advanced.
+//
+// The connection used by Rows is not usable for other queries
+// until Close has been called.
+type Rows struct {
+	conn *baseConn
+	r    *buff.Reader
+	done *buff.DoneReadingSignal
+	q    *gfQuery
+
+	// outDesc is the output descriptor of the query.
+	outDesc descriptor.Descriptor
+
+	// elm is the encoded element of the current row.
+	elm []byte
+
+	// in is the argument encoder for the query's new type IDs
+	// if the server described the query instead of running it.
+	in codecs.Encoder
+
+	// undecodable is set if the server's new descriptors
+	// were not usable so that the results are discarded.
+	undecodable bool
+
+	err       error
+	finished  bool
+	closed    bool
+	completed bool
+	resent    bool
+
+	// release releases the resources held by the iterator.
+	release func(error) error
+}
+
+// newIterQuery returns a new granular flow query for an iterator.
+func newIterQuery(
+	cmd string,
+	args []interface{},
+	headers msgHeaders,
+) *gfQuery {
+	return &gfQuery{
+		cmd:     cmd,
+		fmt:     format.Binary,
+		expCard: cardinality.Many,
+		args:    args,
+		headers: headers,
+	}
+}
+
+// QueryIter runs a query and returns an iterator over the results.
+func (c *baseConn) QueryIter(ctx context.Context, q *gfQuery) (*Rows, error) {
+	r, err := c.acquireReader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if e := c.setDeadline(ctx); e != nil {
+		return nil, e
+	}
+
+	rows, err := c.startIter(r, q)
+	if err != nil {
+		return nil, c.releaseReader(r, err)
+	}
+
+	rows.release = func(err error) error { return c.releaseReader(r, err) }
+	return rows, nil
+}
+
+func (c *baseConn) startIter(r *buff.Reader, q *gfQuery) (*Rows, error) {
+	v1pX := c.protocolVersion.gte(protocolVersion1p0)
+	if !v1pX && len(q.state) > 0 {
+		return nil, errStateNotSupported
+	}
+
+	ids, ok := c.getTypeIDs(q)
+
+	var (
+		inDesc  interface{}
+		outDesc interface{}
+	)
+
+	if ok {
+		inDesc, ok = descCache.Get(ids.in)
+	}
+
+	if ok {
+		outDesc, ok = descCache.Get(ids.out)
+	}
+
+	if !ok {
+		var (
+			descs descPair
+			err   error
+		)
+
+		if v1pX {
+			ids, descs, err = c.parse(r, q)
+		} else {
+			ids, err = c.prepare(r, q)
+			if err == nil {
+				descs, err = c.describe(r, q)
+			}
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		c.putTypeIDs(q, ids)
+		descCache.Put(ids.in, descs.in)
+		descCache.Put(ids.out, descs.out)
+
+		inDesc = descs.in
+		outDesc = descs.out
+	}
+
+	in, err := c.inEncoder(ids.in, inDesc.(descriptor.Descriptor))
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case v1pX:
+		err = c.sendExecute1pX(q, in, ids.out)
+	case ok:
+		err = c.sendOptimistic(q, in, ids)
+	default:
+		err = c.sendExecute(q, in)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Rows{
+		conn:    c,
+		r:       r,
+		done:    buff.NewSignal(),
+		q:       q,
+		outDesc: outDesc.(descriptor.Descriptor),
+	}, nil
+}
+
+// inEncoder returns the cached argument encoder for id
+// or builds it from desc.
+func (c *baseConn) inEncoder(
+	id UUID,
+	desc descriptor.Descriptor,
+) (codecs.Encoder, error) {
+	if in, ok := c.inCodecCache.Get(id); ok {
+		return in.(codecs.Encoder), nil
+	}
+
+	in, err := codecs.BuildEncoder(desc)
+	if err != nil {
+		return nil, &unsupportedFeatureError{msg: err.Error()}
+	}
+
+	c.inCodecCache.Put(id, in)
+	return in, nil
+}
+
+// Next advances the iterator to the next result.
+// Next returns false when there are no more results
+// or an error was encountered. Check Err() after Next returns false.
+func (rs *Rows) Next() bool {
+	rs.elm = nil
+
+	if rs.finished {
+		return false
+	}
+
+	r := rs.r
+
+	for r.Next(rs.done.Chan) {
+		switch r.MsgType {
+		case message.Data:
+			if rs.undecodable {
+				r.DiscardMessage()
+				break
+			}
+
+			elmCount := r.PopUint16()
+			if elmCount != 1 {
+				panic(fmt.Sprintf(
+					"unexpected number of elements: expected 1, got %v",
+					elmCount,
+				))
+			}
+
+			elmLen := r.PopUint32()
+			rs.elm = r.Buf[:elmLen]
+			r.Discard(int(elmLen))
+			return true
+		case message.CommandComplete:
+			rs.completed = true
+			rs.conn.decodeCommandComplete(r)
+		case message.CommandDataDescription:
+			// the server's type descriptors have changed.
+			rs.describe(r)
+		case message.ReadyForCommand:
+			ignoreHeaders(r)
+			r.Discard(1) // transaction state
+
+			// Protocol 0.x servers only describe optimistic executes
+			// that have stale type IDs. The query is sent again.
+			if rs.in != nil && !rs.completed && !rs.resent && rs.err == nil {
+				rs.resent = true
+				rs.err = rs.conn.sendExecute(rs.q, rs.in)
+				if rs.err == nil {
+					break
+				}
+			}
+
+			rs.done.Signal()
+		case message.ErrorResponse:
+			rs.err = wrapAll(rs.err, decodeError(r, rs.q.cmd))
+		default:
+			if e := rs.conn.fallThrough(r); e != nil {
+				// the connection will not be usable after this x_x
+				rs.err = wrapAll(rs.err, e)
+				rs.finished = true
+				return false
+			}
+		}
+	}
+
+	if r.Err != nil {
+		rs.err = wrapAll(rs.err, &clientConnectionError{err: r.Err})
+	}
+
+	rs.finished = true
+	return false
+}
+
+// describe decodes a CommandDataDescription
+// and caches the query's new type IDs and descriptors.
+func (rs *Rows) describe(r *buff.Reader) {
+	ids, descs, err := rs.conn.decodeCommandDataDescription(r, rs.q)
+	if err != nil {
+		rs.undecodable = true
+		rs.err = wrapAll(rs.err, err)
+		return
+	}
+
+	rs.conn.putTypeIDs(rs.q, ids)
+	descCache.Put(ids.in, descs.in)
+	descCache.Put(ids.out, descs.out)
+	rs.outDesc = descs.out
+
+	rs.in, err = rs.conn.inEncoder(ids.in, descs.in)
+	if err != nil {
+		rs.undecodable = true
+		rs.err = wrapAll(rs.err, err)
+	}
+}
+
+// Scan decodes the current result into out.
+// out must be a pointer to a value that matches the query's result type.
+func (rs *Rows) Scan(out interface{}) error {
+	if rs.closed {
+		return &interfaceError{msg: "rows are closed"}
+	}
+
+	if rs.elm == nil {
+		return &interfaceError{msg: "Scan called without a current row"}
+	}
+
+	val, err := marshal.ValueOf(out)
+	if err != nil {
+		return err
+	}
+
+	key := codecKey{ID: rs.outDesc.ID, Type: val.Type()}
+	decoder, ok := rs.conn.outCodecCache.Get(key)
+	if !ok {
+		path := codecs.Path(val.Type().String())
+		decoder, err = codecs.BuildDecoder(rs.outDesc, val.Type(), path)
+		if err != nil {
+			err = fmt.Errorf(
+				"the \"out\" argument does not match query schema: %v",
+				err,
+			)
+			return &unsupportedFeatureError{msg: err.Error()}
+		}
+		rs.conn.outCodecCache.Put(key, decoder)
+	}
+
+	val.Set(reflect.Zero(val.Type()))
+	decoder.(codecs.Decoder).Decode(
+		buff.SimpleReader(rs.elm),
+		unsafe.Pointer(val.UnsafeAddr()),
+	)
+
+	return nil
+}
+
+// Err returns the error, if any, that was encountered during iteration.
+func (rs *Rows) Err() error {
+	return rs.err
+}
+
+// Close reads any remaining results and releases the connection.
+// Close returns the same error as Err if the iteration failed.
+// Calling Close more than once is a no-op.
+func (rs *Rows) Close() error {
+	if rs.closed {
+		return nil
+	}
+
+	for rs.Next() {
+	}
+
+	rs.closed = true
+	return rs.release(rs.err)
+}
@@ -0,0 +1,154 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedb
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryIter(t *testing.T) {
+	ctx := context.Background()
+	p, err := Connect(ctx, opts)
+	require.Nil(t, err)
+	defer p.Close() // nolint:errcheck
+
+	// run twice to exercise the cached codec path.
+	for i := 0; i < 2; i++ {
+		rows, err := p.QueryIter(ctx, "SELECT {1, 2, 3}")
+		require.Nil(t, err)
+
+		var result []int64
+		for rows.Next() {
+			var val int64
+			require.Nil(t, rows.Scan(&val))
+			result = append(result, val)
+		}
+
+		require.Nil(t, rows.Err())
+		require.Nil(t, rows.Close())
+		assert.Equal(t, []int64{1, 2, 3}, result)
+	}
+}
+
+func TestQueryIterObjects(t *testing.T) {
+	ctx := context.Background()
+
+	type Result struct {
+		Name  string `edgedb:"name"`
+		Value int64  `edgedb:"value"`
+	}
+
+	query := `
+		SELECT (
+			SELECT {("a", 1), ("b", 2)}
+		) { name := .0, value := .1 }
+		ORDER BY .name
+	`
+
+	rows, err := conn.QueryIter(ctx, query)
+	require.Nil(t, err)
+
+	var result []Result
+	for rows.Next() {
+		var row Result
+		require.Nil(t, rows.Scan(&row))
+		result = append(result, row)
+	}
+
+	require.Nil(t, rows.Close())
+	expected := []Result{{"a", 1}, {"b", 2}}
+	assert.Equal(t, expected, result)
+}
+
+func TestQueryIterCloseEarly(t *testing.T) {
+	ctx := context.Background()
+
+	rows, err := conn.QueryIter(ctx, "SELECT range_unpack(range(0, 1000))")
+	require.Nil(t, err)
+	require.True(t, rows.Next())
+
+	var val int64
+	err = conn.QueryOne(ctx, "SELECT 1", &val)
+	expected := "edgedb.InterfaceError: " +
+		"Connection is borrowed for a query iterator. " +
+		"Close the iterator before using the connection."
+	assert.EqualError(t, err, expected)
+
+	require.Nil(t, rows.Close())
+	assert.False(t, rows.Next())
+
+	err = conn.QueryOne(ctx, "SELECT 1", &val)
+	require.Nil(t, err)
+	assert.Equal(t, int64(1), val)
+}
+
+func TestQueryIterArgs(t *testing.T) {
+	ctx := context.Background()
+
+	rows, err := conn.QueryIter(ctx, "SELECT <str>$0 ++ <str>$1", "a", "b")
+	require.Nil(t, err)
+
+	require.True(t, rows.Next())
+	var val string
+	require.Nil(t, rows.Scan(&val))
+	assert.Equal(t, "ab", val)
+	assert.False(t, rows.Next())
+	require.Nil(t, rows.Close())
+}
+
+func TestQueryIterError(t *testing.T) {
+	ctx := context.Background()
+
+	rows, err := conn.QueryIter(ctx, "SELECT 1 / 0")
+	require.Nil(t, err)
+
+	assert.False(t, rows.Next())
+	expected := "edgedb.DivisionByZeroError: division by zero"
+	assert.EqualError(t, rows.Err(), expected)
+	assert.EqualError(t, rows.Close(), expected)
+
+	_, err = conn.QueryIter(ctx, "SELECT nope")
+	require.NotNil(t, err)
+
+	var edbErr Error
+	require.True(t, errors.As(err, &edbErr), "wrong error: %v", err)
+	assert.True(t, edbErr.Category(InvalidReferenceError), err)
+}
+
+func TestQueryIterScanWrongType(t *testing.T) {
+	ctx := context.Background()
+
+	rows, err := conn.QueryIter(ctx, "SELECT 1")
+	require.Nil(t, err)
+	defer rows.Close() // nolint:errcheck
+
+	require.True(t, rows.Next())
+	var val string
+	err = rows.Scan(&val)
+	assert.EqualError(
+		t,
+		err,
+		"edgedb.UnsupportedFeatureError: "+
+			"the \"out\" argument does not match query schema: "+
+			"expected string to be int64 got string",
+	)
+}
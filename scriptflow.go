@@ -33,7 +33,7 @@ func ignoreHeaders(r *buff.Reader) {
 func copyHeaders(h msgHeaders) msgHeaders {
 	cpy := make(msgHeaders, len(h))
 
-	for key, val := range cpy {
+	for key, val := range h {
 		cpy[key] = val
 	}
 
@@ -51,6 +51,14 @@ func writeHeaders(w *buff.Writer, headers msgHeaders) {
 }
 
 func (c *baseConn) scriptFlow(r *buff.Reader, q sfQuery) error {
+	if c.protocolVersion.gte(protocolVersion1p0) {
+		return c.scriptFlow1pX(r, q)
+	}
+
+	if len(q.state) > 0 {
+		return errStateNotSupported
+	}
+
 	w := buff.NewWriter(c.writeMemory[:0])
 	w.BeginMessage(message.ExecuteScript)
 	writeHeaders(w, q.headers)
@@ -0,0 +1,51 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedb
+
+import "sync"
+
+// settingsStore holds the parameters reported by the server
+// with ParameterStatus messages.
+// It is safe for concurrent use and is shared by all pool connections.
+type settingsStore struct {
+	mu     sync.RWMutex
+	values map[string]string
+}
+
+func newSettingsStore() *settingsStore {
+	return &settingsStore{values: make(map[string]string)}
+}
+
+func (s *settingsStore) set(name, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values[name] = value
+}
+
+// copy returns a copy of the stored settings.
+func (s *settingsStore) copy() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	cpy := make(map[string]string, len(s.values))
+	for name, value := range s.values {
+		cpy[name] = value
+	}
+
+	return cpy
+}
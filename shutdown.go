@@ -0,0 +1,213 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedb
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Leak describes a connection that was still acquired
+// when it was closed by Pool.Shutdown.
+type Leak struct {
+	// AcquiredAt is when the connection was acquired.
+	AcquiredAt time.Time
+
+	// Stack is the stack trace of the goroutine that acquired the connection.
+	// It is only recorded if Options.TraceAcquires is true.
+	Stack string
+}
+
+// LeakError is wrapped by the error returned from Pool.Shutdown
+// when acquired connections had to be closed.
+// Use errors.As to get the leaked connections.
+type LeakError struct {
+	Leaks []Leak
+}
+
+func (e *LeakError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(
+		&b,
+		"%v acquired connection(s) were not released before shutdown",
+		len(e.Leaks),
+	)
+
+	for _, leak := range e.Leaks {
+		fmt.Fprintf(&b, "\nacquired at %v", leak.AcquiredAt)
+		if leak.Stack != "" {
+			fmt.Fprintf(&b, ":\n%v", leak.Stack)
+		}
+	}
+
+	return b.String()
+}
+
+// acquisition is a connection that is acquired from a pool.
+type acquisition struct {
+	// socket is the connection's socket when it was acquired.
+	// It is closed to interrupt the connection's user on shutdown.
+	socket net.Conn
+	leak   Leak
+}
+
+// acquiredConns tracks the connections that are acquired from a pool.
+type acquiredConns struct {
+	mu    sync.Mutex
+	conns map[*reconnectingConn]*acquisition
+
+	// shutdown is set once the acquired connections have been closed.
+	shutdown bool
+}
+
+func newAcquiredConns() *acquiredConns {
+	return &acquiredConns{conns: make(map[*reconnectingConn]*acquisition)}
+}
+
+func (a *acquiredConns) add(conn *reconnectingConn, traceStack bool) {
+	acq := &acquisition{leak: Leak{AcquiredAt: time.Now()}}
+	if conn.conn != nil {
+		acq.socket = conn.conn.conn
+	}
+
+	if traceStack {
+		acq.leak.Stack = string(debug.Stack())
+	}
+
+	a.mu.Lock()
+	a.conns[conn] = acq
+	a.mu.Unlock()
+}
+
+// remove unregisters conn and reports
+// if the acquired connections have already been closed.
+func (a *acquiredConns) remove(conn *reconnectingConn) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.conns, conn)
+	return a.shutdown
+}
+
+// closeAll closes the sockets of all acquired connections
+// and returns their leaks.
+func (a *acquiredConns) closeAll() []Leak {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.shutdown = true
+	leaks := make([]Leak, 0, len(a.conns))
+	for conn, acq := range a.conns {
+		if acq.socket != nil {
+			_ = acq.socket.Close()
+		}
+
+		leaks = append(leaks, acq.leak)
+		delete(a.conns, conn)
+	}
+
+	return leaks
+}
+
+// Shutdown closes all connections in the pool.
+// New connections can not be acquired once Shutdown is called.
+// Shutdown waits for acquired connections to be released
+// until ctx is done. Connections that are still acquired then are closed
+// and an error wrapping a *LeakError is returned.
+// Shutdown returns an error if the pool was already closed.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	if *p.isClosed {
+		p.mu.Unlock()
+		return &interfaceError{msg: "pool closed"}
+	}
+	*p.isClosed = true
+	p.mu.Unlock()
+
+	// p.mu is not held while draining
+	// so that goroutines waiting to acquire can see the pool is closed.
+	close(p.healthCheckDone)
+	p.capacity.wake()
+
+	var (
+		wg    sync.WaitGroup
+		errMu sync.Mutex
+		errs  []error
+	)
+
+	closeConn := func(conn *reconnectingConn) {
+		atomic.AddInt64(&p.stats.closed, 1)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := conn.close()
+
+			errMu.Lock()
+			errs = append(errs, err)
+			errMu.Unlock()
+		}()
+	}
+
+	timedOut := false
+	for drained := 0; !timedOut && p.capacity.outstanding(drained) > 0; {
+		freeConns, potentialConns, changed := p.capacity.chans()
+
+		select {
+		case conn := <-freeConns:
+			drained++
+			closeConn(conn)
+		case <-potentialConns:
+			drained++
+		case <-changed:
+		case <-ctx.Done():
+			timedOut = true
+			leaks := p.acquired.closeAll()
+			if len(leaks) > 0 {
+				errMu.Lock()
+				errs = append(errs, &interfaceError{
+					err: &LeakError{Leaks: leaks},
+				})
+				errMu.Unlock()
+			}
+		}
+	}
+
+	// close connections that were released while timing out.
+	freeConns, _, _ := p.capacity.chans()
+	for drained := false; !drained; {
+		select {
+		case conn := <-freeConns:
+			closeConn(conn)
+		default:
+			drained = true
+		}
+	}
+
+	wg.Wait()
+
+	if p.replicas != nil {
+		errs = append(errs, p.replicas.shutdown(ctx))
+	}
+
+	return wrapAll(errs...)
+}
@@ -0,0 +1,128 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdownWaitsForRelease(t *testing.T) {
+	server := startStallingServer(t)
+	defer server.close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p, err := Connect(ctx, server.options())
+	require.Nil(t, err)
+
+	conn, err := p.Acquire(ctx)
+	require.Nil(t, err)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_ = conn.Release()
+	}()
+
+	require.Nil(t, p.Shutdown(ctx))
+
+	_, err = p.Acquire(ctx)
+	assert.EqualError(t, err, "edgedb.InterfaceError: pool closed")
+	assert.Equal(t, 0, p.Stat().TotalConns)
+}
+
+func TestShutdownReportsLeaks(t *testing.T) {
+	server := startStallingServer(t)
+	defer server.close()
+
+	o := server.options()
+	o.TraceAcquires = true
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p, err := Connect(ctx, o)
+	require.Nil(t, err)
+
+	conn, err := p.Acquire(ctx)
+	require.Nil(t, err)
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(
+		ctx,
+		50*time.Millisecond,
+	)
+	defer shutdownCancel()
+
+	err = p.Shutdown(shutdownCtx)
+	var leakErr *LeakError
+	require.True(t, errors.As(err, &leakErr), "wrong error: %v", err)
+	require.Equal(t, 1, len(leakErr.Leaks))
+	assert.Contains(t, leakErr.Leaks[0].Stack, "TestShutdownReportsLeaks")
+
+	var edbErr Error
+	require.True(t, errors.As(err, &edbErr), "wrong error: %v", err)
+	assert.True(t, edbErr.Category(InterfaceError), "wrong error: %v", err)
+
+	// the leaked connection was closed.
+	assert.NotNil(t, conn.Execute(ctx, "SELECT 1"))
+	_ = conn.Release()
+	assert.Equal(t, 0, p.Stat().TotalConns)
+}
+
+func TestShutdownWithWaitingAcquirer(t *testing.T) {
+	server := startStallingServer(t)
+	defer server.close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p, err := Connect(ctx, server.options())
+	require.Nil(t, err)
+
+	// the only connection is leaked.
+	_, err = p.Acquire(ctx)
+	require.Nil(t, err)
+
+	waiting := make(chan error)
+	go func() { waiting <- p.Execute(ctx, "SELECT 1") }()
+	time.Sleep(20 * time.Millisecond)
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(
+		ctx,
+		50*time.Millisecond,
+	)
+	defer shutdownCancel()
+
+	start := time.Now()
+	err = p.Shutdown(shutdownCtx)
+	var leakErr *LeakError
+	require.True(t, errors.As(err, &leakErr), "wrong error: %v", err)
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+
+	select {
+	case err = <-waiting:
+		assert.EqualError(t, err, "edgedb.InterfaceError: pool closed")
+	case <-time.After(time.Second):
+		t.Fatal("acquire was not woken by Shutdown")
+	}
+}
@@ -0,0 +1,171 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedb
+
+import (
+	"strings"
+
+	"github.com/edgedb/edgedb-go/internal/buff"
+	"github.com/edgedb/edgedb-go/internal/codecs"
+	"github.com/edgedb/edgedb-go/internal/descriptor"
+)
+
+/*
+Session state is sent with every query on protocol 1.0 and later.
+It is encoded with the state descriptor sent by the server
+and has the following shape:
+
+	module:  str
+	aliases: array<tuple<str, str>>
+	config:  input shape of session config settings
+	globals: input shape of global variables
+
+Only the values that have been set are sent.
+*/
+
+var errStateNotSupported = &unsupportedFeatureError{
+	msg: "session state requires protocol 1.0 or later, " +
+		"the server does not support it",
+}
+
+// encodeState writes the state descriptor ID and state data.
+func (c *baseConn) encodeState(
+	w *buff.Writer,
+	state map[string]interface{},
+) error {
+	if len(state) == 0 {
+		w.PushUUID(descriptor.IDZero)
+		w.PushUint32(0) // no state data
+		return nil
+	}
+
+	encoder, err := c.stateEncoder()
+	if err != nil {
+		return err
+	}
+
+	w.PushUUID(encoder.DescriptorID())
+	if e := encoder.Encode(w, state, codecs.Path("state")); e != nil {
+		return &invalidArgumentError{
+			msg: "invalid session state: " + e.Error(),
+		}
+	}
+
+	return nil
+}
+
+func (c *baseConn) stateEncoder() (codecs.Encoder, error) {
+	if c.stateDesc.ID == descriptor.IDZero {
+		return nil, &binaryProtocolError{
+			msg: "the server did not send a state descriptor",
+		}
+	}
+
+	if encoder, ok := c.inCodecCache.Get(c.stateDesc.ID); ok {
+		return encoder.(codecs.Encoder), nil
+	}
+
+	encoder, err := codecs.BuildEncoder(c.stateDesc)
+	if err != nil {
+		return nil, &unsupportedFeatureError{msg: err.Error()}
+	}
+
+	c.inCodecCache.Put(c.stateDesc.ID, encoder)
+	return encoder, nil
+}
+
+// copyState returns a copy of state that can be modified
+// without changing state.
+func copyState(state map[string]interface{}) map[string]interface{} {
+	cpy := make(map[string]interface{}, len(state)+1)
+	for key, val := range state {
+		cpy[key] = val
+	}
+
+	return cpy
+}
+
+// withStateValues returns a copy of state with values merged
+// into the input shape stored at key.
+func withStateValues(
+	state map[string]interface{},
+	key string,
+	values map[string]interface{},
+) map[string]interface{} {
+	merged := make(map[string]interface{})
+	if prev, ok := state[key].(map[string]interface{}); ok {
+		for name, val := range prev {
+			merged[name] = val
+		}
+	}
+
+	for name, val := range values {
+		merged[name] = val
+	}
+
+	state = copyState(state)
+	state[key] = merged
+	return state
+}
+
+// qualifyGlobals returns a copy of globals where names without a module
+// are qualified with the default module.
+func qualifyGlobals(globals map[string]interface{}) map[string]interface{} {
+	qualified := make(map[string]interface{}, len(globals))
+	for name, val := range globals {
+		if !strings.Contains(name, "::") {
+			name = "default::" + name
+		}
+
+		qualified[name] = val
+	}
+
+	return qualified
+}
+
+// withModuleAliases returns a copy of state with aliases merged
+// into the existing aliases.
+func withModuleAliases(
+	state map[string]interface{},
+	aliases []ModuleAlias,
+) map[string]interface{} {
+	var merged []interface{}
+	if prev, ok := state["aliases"].([]interface{}); ok {
+		merged = append(merged, prev...)
+	}
+
+	for _, alias := range aliases {
+		pair := []interface{}{alias.Alias, alias.Module}
+
+		replaced := false
+		for i, prev := range merged {
+			if prev.([]interface{})[0] == alias.Alias {
+				merged[i] = pair
+				replaced = true
+				break
+			}
+		}
+
+		if !replaced {
+			merged = append(merged, pair)
+		}
+	}
+
+	state = copyState(state)
+	state["aliases"] = merged
+	return state
+}
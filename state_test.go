@@ -0,0 +1,98 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithStateValuesDoesNotModifyParent(t *testing.T) {
+	parent := withStateValues(
+		nil,
+		"globals",
+		map[string]interface{}{"default::a": int64(1)},
+	)
+
+	child := withStateValues(
+		parent,
+		"globals",
+		map[string]interface{}{"default::b": int64(2)},
+	)
+
+	assert.Equal(
+		t,
+		map[string]interface{}{
+			"globals": map[string]interface{}{"default::a": int64(1)},
+		},
+		parent,
+	)
+
+	assert.Equal(
+		t,
+		map[string]interface{}{
+			"globals": map[string]interface{}{
+				"default::a": int64(1),
+				"default::b": int64(2),
+			},
+		},
+		child,
+	)
+}
+
+func TestQualifyGlobals(t *testing.T) {
+	globals := qualifyGlobals(map[string]interface{}{
+		"a":         "x",
+		"my_mod::b": "y",
+	})
+
+	assert.Equal(
+		t,
+		map[string]interface{}{"default::a": "x", "my_mod::b": "y"},
+		globals,
+	)
+}
+
+func TestWithModuleAliasesReplacesAlias(t *testing.T) {
+	parent := withModuleAliases(nil, []ModuleAlias{
+		{Alias: "a", Module: "mod_a"},
+		{Alias: "b", Module: "mod_b"},
+	})
+
+	child := withModuleAliases(parent, []ModuleAlias{
+		{Alias: "a", Module: "other"},
+	})
+
+	assert.Equal(
+		t,
+		[]interface{}{
+			[]interface{}{"a", "mod_a"},
+			[]interface{}{"b", "mod_b"},
+		},
+		parent["aliases"],
+	)
+
+	assert.Equal(
+		t,
+		[]interface{}{
+			[]interface{}{"a", "other"},
+			[]interface{}{"b", "mod_b"},
+		},
+		child["aliases"],
+	)
+}
@@ -0,0 +1,173 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedb
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// tlsALPNProtocol is the application protocol negotiated during
+// the TLS handshake.
+const tlsALPNProtocol = "edgedb-binary"
+
+func validateTLSSecurity(mode TLSSecurityMode) error {
+	switch mode {
+	case "", TLSModeDefault, TLSModeStrict,
+		TLSModeNoHostVerification, TLSModeInsecure:
+		return nil
+	default:
+		return &configurationError{msg: fmt.Sprintf(
+			"invalid TLS security mode: %q, must be one of "+
+				"default, strict, no_host_verification or insecure",
+			mode,
+		)}
+	}
+}
+
+// resolveTLSSecurity returns the concrete security mode to use.
+// An empty mode is returned if TLS should not be used.
+func resolveTLSSecurity(
+	mode TLSSecurityMode,
+	caData []byte,
+) (TLSSecurityMode, error) {
+	if e := validateTLSSecurity(mode); e != nil {
+		return "", e
+	}
+
+	if len(caData) > 0 && !x509.NewCertPool().AppendCertsFromPEM(caData) {
+		return "", &configurationError{
+			msg: "could not parse TLS certificate authority data",
+		}
+	}
+
+	switch {
+	case mode != "" && mode != TLSModeDefault:
+		return mode, nil
+	case len(caData) > 0:
+		return TLSModeNoHostVerification, nil
+	case mode == TLSModeDefault:
+		return TLSModeStrict, nil
+	default:
+		return "", nil
+	}
+}
+
+// newTLSConfig returns the TLS configuration for connecting to host
+// or nil if TLS should not be used.
+func (c *connConfig) newTLSConfig(host string) *tls.Config {
+	if c.tlsConfig != nil {
+		cfg := c.tlsConfig.Clone()
+		if cfg.ServerName == "" {
+			cfg.ServerName = host
+		}
+
+		if len(cfg.NextProtos) == 0 {
+			cfg.NextProtos = []string{tlsALPNProtocol}
+		}
+
+		return cfg
+	}
+
+	if c.tlsSecurity == "" {
+		return nil
+	}
+
+	var roots *x509.CertPool
+	if len(c.tlsCAData) > 0 {
+		roots = x509.NewCertPool()
+		roots.AppendCertsFromPEM(c.tlsCAData)
+	}
+
+	cfg := &tls.Config{
+		ServerName: host,
+		RootCAs:    roots,
+		NextProtos: []string{tlsALPNProtocol},
+		MinVersion: tls.VersionTLS12,
+	}
+
+	switch c.tlsSecurity {
+	case TLSModeInsecure:
+		cfg.InsecureSkipVerify = true
+	case TLSModeNoHostVerification:
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyPeerCertificate = verifyCertificateChain(roots)
+	}
+
+	return cfg
+}
+
+// verifyCertificateChain returns a tls.Config.VerifyPeerCertificate function
+// that verifies the certificate chain without verifying the host name.
+func verifyCertificateChain(
+	roots *x509.CertPool,
+) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("server did not present a certificate")
+		}
+
+		certs := make([]*x509.Certificate, len(rawCerts))
+		for i, raw := range rawCerts {
+			cert, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return err
+			}
+			certs[i] = cert
+		}
+
+		intermediates := x509.NewCertPool()
+		for _, cert := range certs[1:] {
+			intermediates.AddCert(cert)
+		}
+
+		_, err := certs[0].Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+		})
+		return err
+	}
+}
+
+// upgradeTLS performs a TLS handshake on the connection
+// if the configuration requires TLS.
+func (c *baseConn) upgradeTLS(addr *dialArgs) error {
+	if addr.network != "tcp" {
+		return nil
+	}
+
+	host, _, err := net.SplitHostPort(addr.address)
+	if err != nil {
+		return err
+	}
+
+	cfg := c.cfg.newTLSConfig(host)
+	if cfg == nil {
+		return nil
+	}
+
+	conn := tls.Client(c.conn, cfg)
+	if e := conn.Handshake(); e != nil {
+		return e
+	}
+
+	c.conn = conn
+	return nil
+}
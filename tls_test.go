@@ -0,0 +1,223 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedb
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/edgedb/edgedb-go/internal/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// selfSignedCert returns a TLS certificate and its PEM encoding.
+func selfSignedCert(t *testing.T, hosts ...string) (tls.Certificate, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+
+	template := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{Organization: []string{"EdgeDB"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, h)
+		}
+	}
+
+	der, err := x509.CreateCertificate(
+		rand.Reader, &template, &template, &key.PublicKey, key)
+	require.Nil(t, err)
+
+	cert := tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	block := &pem.Block{Type: "CERTIFICATE", Bytes: der}
+	return cert, pem.EncodeToMemory(block)
+}
+
+type tlsHandshakeResult struct {
+	protocol string
+	msgType  uint8
+}
+
+// startTLSServer accepts a single TLS connection
+// and reports the negotiated protocol and the first message type received.
+func startTLSServer(
+	t *testing.T,
+	cert tls.Certificate,
+) (*dialArgs, chan tlsHandshakeResult) {
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{tlsALPNProtocol},
+	})
+	require.Nil(t, err)
+
+	results := make(chan tlsHandshakeResult, 1)
+	go func() {
+		defer ln.Close() // nolint:errcheck
+
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close() // nolint:errcheck
+
+		tlsConn := conn.(*tls.Conn)
+		if tlsConn.Handshake() != nil {
+			return
+		}
+
+		buf := make([]byte, 1)
+		if _, err := conn.Read(buf); err != nil {
+			return
+		}
+
+		results <- tlsHandshakeResult{
+			protocol: tlsConn.ConnectionState().NegotiatedProtocol,
+			msgType:  buf[0],
+		}
+	}()
+
+	return &dialArgs{"tcp", ln.Addr().String()}, results
+}
+
+func connectTLS(addr *dialArgs, cfg *connConfig) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return connectWithTimeout(ctx, &baseConn{cfg: cfg}, addr)
+}
+
+func TestTLSStrict(t *testing.T) {
+	cert, ca := selfSignedCert(t, "127.0.0.1")
+	addr, results := startTLSServer(t, cert)
+
+	err := connectTLS(addr, &connConfig{
+		tlsSecurity: TLSModeStrict,
+		tlsCAData:   ca,
+	})
+	require.NotNil(t, err)
+
+	result := <-results
+	assert.Equal(t, tlsALPNProtocol, result.protocol)
+	assert.Equal(t, uint8(message.ClientHandshake), result.msgType)
+}
+
+func TestTLSStrictUnknownAuthority(t *testing.T) {
+	cert, _ := selfSignedCert(t, "127.0.0.1")
+	addr, _ := startTLSServer(t, cert)
+
+	err := connectTLS(addr, &connConfig{tlsSecurity: TLSModeStrict})
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "certificate")
+
+	var edbErr Error
+	require.True(t, errors.As(err, &edbErr), "wrong error: %v", err)
+	assert.True(
+		t,
+		edbErr.Category(ClientConnectionFailedError),
+		"wrong error: %v",
+		err,
+	)
+}
+
+func TestTLSNoHostVerification(t *testing.T) {
+	cert, ca := selfSignedCert(t, "edgedb.example.com")
+
+	addr, _ := startTLSServer(t, cert)
+	err := connectTLS(addr, &connConfig{
+		tlsSecurity: TLSModeStrict,
+		tlsCAData:   ca,
+	})
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "certificate")
+
+	addr, results := startTLSServer(t, cert)
+	_ = connectTLS(addr, &connConfig{
+		tlsSecurity: TLSModeNoHostVerification,
+		tlsCAData:   ca,
+	})
+
+	result := <-results
+	assert.Equal(t, uint8(message.ClientHandshake), result.msgType)
+}
+
+func TestTLSInsecure(t *testing.T) {
+	cert, _ := selfSignedCert(t, "edgedb.example.com")
+	addr, results := startTLSServer(t, cert)
+
+	_ = connectTLS(addr, &connConfig{tlsSecurity: TLSModeInsecure})
+
+	result := <-results
+	assert.Equal(t, uint8(message.ClientHandshake), result.msgType)
+}
+
+func TestResolveTLSSecurity(t *testing.T) {
+	_, ca := selfSignedCert(t, "localhost")
+
+	mode, err := resolveTLSSecurity("", nil)
+	require.Nil(t, err)
+	assert.Equal(t, TLSSecurityMode(""), mode)
+
+	mode, err = resolveTLSSecurity(TLSModeDefault, nil)
+	require.Nil(t, err)
+	assert.Equal(t, TLSModeStrict, mode)
+
+	mode, err = resolveTLSSecurity("", ca)
+	require.Nil(t, err)
+	assert.Equal(t, TLSModeNoHostVerification, mode)
+
+	mode, err = resolveTLSSecurity(TLSModeStrict, ca)
+	require.Nil(t, err)
+	assert.Equal(t, TLSModeStrict, mode)
+
+	_, err = resolveTLSSecurity("invalid", nil)
+	assert.EqualError(
+		t,
+		err,
+		`edgedb.ConfigurationError: invalid TLS security mode: "invalid", `+
+			"must be one of default, strict, no_host_verification or insecure",
+	)
+
+	_, err = resolveTLSSecurity("", []byte("not a certificate"))
+	assert.EqualError(
+		t,
+		err,
+		"edgedb.ConfigurationError: "+
+			"could not parse TLS certificate authority data",
+	)
+}
@@ -39,6 +39,11 @@ type Tx struct {
 	conn    *baseConn
 	state   transactionState
 	options TxOptions
+
+	// sessionState is the session state sent with each query.
+	sessionState map[string]interface{}
+
+	queryOpts queryOptions
 }
 
 func (t *Tx) execute(
@@ -46,7 +51,7 @@ func (t *Tx) execute(
 	cmd string,
 	sucessState transactionState,
 ) error {
-	err := t.conn.ScriptFlow(ctx, sfQuery{cmd: cmd})
+	err := t.scriptFlow(ctx, sfQuery{cmd: cmd})
 
 	switch err {
 	case nil:
@@ -58,6 +63,16 @@ func (t *Tx) execute(
 	return err
 }
 
+func (t *Tx) scriptFlow(ctx context.Context, q sfQuery) error {
+	q.state = t.sessionState
+	return t.conn.ScriptFlow(ctx, q)
+}
+
+func (t *Tx) granularFlow(ctx context.Context, q *gfQuery) error {
+	q.state = t.sessionState
+	return t.conn.GranularFlow(ctx, q)
+}
+
 // assertNotDone returns an error if the transaction is in a done state.
 func (t *Tx) assertNotDone(opName string) error {
 	switch t.state {
@@ -129,7 +144,8 @@ func (t *Tx) Execute(ctx context.Context, cmd string) error {
 		return e
 	}
 
-	return t.conn.ScriptFlow(ctx, sfQuery{cmd: cmd})
+	hdrs := t.queryOpts.scriptHeaders(0)
+	return t.scriptFlow(ctx, sfQuery{cmd: cmd, headers: hdrs})
 }
 
 // Query runs a query and returns the results.
@@ -143,12 +159,19 @@ func (t *Tx) Query(
 		return e
 	}
 
-	q, err := newQuery(cmd, format.Binary, cardinality.Many, args, nil, out)
+	q, err := newQuery(
+		cmd,
+		format.Binary,
+		cardinality.Many,
+		args,
+		t.queryOpts.headers(0),
+		out,
+	)
 	if err != nil {
 		return err
 	}
 
-	return t.conn.GranularFlow(ctx, q)
+	return t.granularFlow(ctx, q)
 }
 
 // QueryOne runs a singleton-returning query and returns its element.
@@ -164,12 +187,19 @@ func (t *Tx) QueryOne(
 		return e
 	}
 
-	q, err := newQuery(cmd, format.Binary, cardinality.One, args, nil, out)
+	q, err := newQuery(
+		cmd,
+		format.Binary,
+		cardinality.One,
+		args,
+		t.queryOpts.headers(0),
+		out,
+	)
 	if err != nil {
 		return err
 	}
 
-	return t.conn.GranularFlow(ctx, q)
+	return t.granularFlow(ctx, q)
 }
 
 // QueryJSON runs a query and return the results as JSON.
@@ -183,12 +213,19 @@ func (t *Tx) QueryJSON(
 		return e
 	}
 
-	q, err := newQuery(cmd, format.JSON, cardinality.Many, args, nil, out)
+	q, err := newQuery(
+		cmd,
+		format.JSON,
+		cardinality.Many,
+		args,
+		t.queryOpts.headers(0),
+		out,
+	)
 	if err != nil {
 		return err
 	}
 
-	return t.conn.GranularFlow(ctx, q)
+	return t.granularFlow(ctx, q)
 }
 
 // QueryOneJSON runs a singleton-returning query.
@@ -204,10 +241,33 @@ func (t *Tx) QueryOneJSON(
 		return e
 	}
 
-	q, err := newQuery(cmd, format.JSON, cardinality.One, args, nil, out)
+	q, err := newQuery(
+		cmd,
+		format.JSON,
+		cardinality.One,
+		args,
+		t.queryOpts.headers(0),
+		out,
+	)
 	if err != nil {
 		return err
 	}
 
-	return t.conn.GranularFlow(ctx, q)
+	return t.granularFlow(ctx, q)
+}
+
+// QueryIter runs a query and returns an iterator over the results.
+// The transaction is not usable until the iterator is closed.
+func (t *Tx) QueryIter(
+	ctx context.Context,
+	cmd string,
+	args ...interface{},
+) (*Rows, error) {
+	if e := t.assertStarted("QueryIter"); e != nil {
+		return nil, e
+	}
+
+	q := newIterQuery(cmd, args, t.queryOpts.headers(0))
+	q.state = t.sessionState
+	return t.conn.QueryIter(ctx, q)
 }
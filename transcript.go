@@ -0,0 +1,431 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedb
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/edgedb/edgedb-go/internal/buff"
+	"github.com/edgedb/edgedb-go/internal/cardinality"
+	"github.com/edgedb/edgedb-go/internal/message"
+)
+
+// A transcript is a text file with one line for each event of a connection.
+// Lines starting with # are descriptions of the message on the next line
+// and are ignored when the transcript is replayed.
+//
+//	1 open tcp 127.0.0.1:5656
+//	# 1 C ClientHandshake version=1.0 database="edgedb" user="edgedb"
+//	1 C 5600000027...
+//	1 close
+//
+// The first field is the connection number.
+// Messages are C for client messages and S for server messages
+// followed by the hex encoded message.
+// The SCRAM data of authentication messages and the server key data
+// are replaced with zeros.
+const (
+	transcriptOpen   = "open"
+	transcriptClose  = "close"
+	transcriptClient = "C"
+	transcriptServer = "S"
+)
+
+var (
+	// transcriptMu serializes writes to transcripts
+	// so that lines from different connections are not interleaved.
+	transcriptMu sync.Mutex
+
+	// transcriptConns is the number of recorded connections.
+	transcriptConns uint64
+)
+
+// recordingConn writes the messages sent and received on a connection
+// to a transcript.
+type recordingConn struct {
+	net.Conn
+	w  io.Writer
+	id uint64
+
+	mu      sync.Mutex
+	sent    []byte
+	recv    []byte
+	version version
+	closed  bool
+}
+
+// recordTranscript returns conn wrapped so that its messages
+// are written to w.
+func recordTranscript(w io.Writer, conn net.Conn, addr *dialArgs) net.Conn {
+	c := &recordingConn{
+		Conn:    conn,
+		w:       w,
+		id:      atomic.AddUint64(&transcriptConns, 1),
+		version: protocolVersionMax,
+	}
+
+	c.writeLines(fmt.Sprintf(
+		"%v %v %v %v\n", c.id, transcriptOpen, addr.network, addr.address,
+	))
+
+	return c
+}
+
+func (c *recordingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.mu.Lock()
+		c.recv = c.record(transcriptServer, append(c.recv, b[:n]...))
+		c.mu.Unlock()
+	}
+
+	return n, err
+}
+
+func (c *recordingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if n > 0 {
+		c.mu.Lock()
+		c.sent = c.record(transcriptClient, append(c.sent, b[:n]...))
+		c.mu.Unlock()
+	}
+
+	return n, err
+}
+
+func (c *recordingConn) Close() error {
+	c.mu.Lock()
+	if !c.closed {
+		c.closed = true
+		c.writeLines(fmt.Sprintf("%v %v\n", c.id, transcriptClose))
+	}
+	c.mu.Unlock()
+
+	return c.Conn.Close()
+}
+
+// record writes the complete messages in buf
+// and returns the bytes of the incomplete message that remain.
+// c.mu must be held.
+func (c *recordingConn) record(direction string, buf []byte) []byte {
+	var b strings.Builder
+
+	for len(buf) >= 5 {
+		n := 1 + int(binary.BigEndian.Uint32(buf[1:5]))
+		if len(buf) < n {
+			break
+		}
+
+		msg := redactMessage(direction, buf[:n])
+		buf = buf[n:]
+
+		if direction == transcriptServer &&
+			msg[0] == message.ServerHandshake && len(msg) >= 9 {
+			c.version = version{
+				binary.BigEndian.Uint16(msg[5:7]),
+				binary.BigEndian.Uint16(msg[7:9]),
+			}
+		}
+
+		fmt.Fprintf(
+			&b,
+			"# %v %v %v\n%v %v %v\n",
+			c.id,
+			direction,
+			describeMessage(direction, msg[0], msg[5:], c.version),
+			c.id,
+			direction,
+			hex.EncodeToString(msg),
+		)
+	}
+
+	if b.Len() > 0 {
+		c.writeLines(b.String())
+	}
+
+	// the remaining bytes are copied
+	// so that buf's array is not retained.
+	return append([]byte(nil), buf...)
+}
+
+// redactMessage returns a copy of msg
+// with credentials and key data replaced with zeros.
+// Other messages are returned unchanged.
+func redactMessage(direction string, msg []byte) []byte {
+	// secret is the offset of the secret data in msg.
+	secret := len(msg)
+
+	switch {
+	case direction == transcriptClient &&
+		msg[0] == message.AuthenticationSASLInitialResponse &&
+		len(msg) >= 9:
+		// method name length and name, then the data length.
+		secret = 9 + int(binary.BigEndian.Uint32(msg[5:9])) + 4
+	case direction == transcriptClient &&
+		msg[0] == message.AuthenticationSASLResponse:
+		secret = 5 + 4 // data length
+	case direction == transcriptServer &&
+		msg[0] == message.Authentication && len(msg) >= 9:
+		status := binary.BigEndian.Uint32(msg[5:9])
+		if status == 0xb || status == 0xc {
+			secret = 9 + 4 // data length
+		}
+	case direction == transcriptServer &&
+		msg[0] == message.ServerKeyData:
+		secret = 5
+	}
+
+	if secret > len(msg) {
+		// the message is malformed so all of it is redacted.
+		secret = 5
+	}
+
+	if secret == len(msg) {
+		return msg
+	}
+
+	redacted := make([]byte, len(msg))
+	copy(redacted, msg[:secret])
+	return redacted
+}
+
+// writeLines writes lines to the transcript.
+// Errors are ignored because recording must not break the connection.
+func (c *recordingConn) writeLines(lines string) {
+	transcriptMu.Lock()
+	defer transcriptMu.Unlock()
+
+	_, _ = io.WriteString(c.w, lines)
+}
+
+// describeMessage returns the name and decoded fields of a message.
+func describeMessage(
+	direction string,
+	mType uint8,
+	payload []byte,
+	v version,
+) (description string) {
+	name := messageName(direction, mType, v)
+
+	defer func() {
+		if recover() != nil {
+			description = name + " (malformed)"
+		}
+	}()
+
+	r := buff.SimpleReader(payload)
+	var fields []string
+	add := func(format string, args ...interface{}) {
+		fields = append(fields, fmt.Sprintf(format, args...))
+	}
+
+	if direction == transcriptClient {
+		describeClientMessage(r, name, add)
+	} else {
+		describeServerMessage(r, name, v, add)
+	}
+
+	if len(fields) == 0 {
+		return name
+	}
+
+	return name + " " + strings.Join(fields, " ")
+}
+
+// messageName returns the name of a message type.
+// Some message types were renamed in protocol 1.0.
+func messageName(direction string, mType uint8, v version) string {
+	names := serverMessageNames
+	if direction == transcriptClient {
+		names = clientMessageNames
+		if v.lt(protocolVersion1p0) {
+			switch mType {
+			case message.Prepare:
+				return "Prepare"
+			case message.OptimisticExecute:
+				return "OptimisticExecute"
+			}
+		}
+	}
+
+	if name, ok := names[mType]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("Unknown(0x%x)", mType)
+}
+
+var clientMessageNames = map[uint8]string{
+	message.AuthenticationSASLInitialResponse: "AuthenticationSASLInitialResponse", // nolint:lll
+	message.AuthenticationSASLResponse:        "AuthenticationSASLResponse",
+	message.ClientHandshake:                   "ClientHandshake",
+	message.DescribeStatement:                 "DescribeStatement",
+	message.Dump:                              "Dump",
+	message.Execute:                           "Execute",
+	message.Execute0pX:                        "Execute0pX",
+	message.ExecuteScript:                     "ExecuteScript",
+	message.Flush:                             "Flush",
+	message.Parse:                             "Parse",
+	message.Restore:                           "Restore",
+	message.RestoreBlock:                      "RestoreBlock",
+	message.RestoreEOF:                        "RestoreEOF",
+	message.Sync:                              "Sync",
+	message.Terminate:                         "Terminate",
+}
+
+var serverMessageNames = map[uint8]string{
+	message.Authentication:         "Authentication",
+	message.CommandComplete:        "CommandComplete",
+	message.CommandDataDescription: "CommandDataDescription",
+	message.Data:                   "Data",
+	message.DumpBlock:              "DumpBlock",
+	message.DumpHeader:             "DumpHeader",
+	message.ErrorResponse:          "ErrorResponse",
+	message.LogMessage:             "LogMessage",
+	message.ParameterStatus:        "ParameterStatus",
+	message.PrepareComplete:        "PrepareComplete",
+	message.ReadyForCommand:        "ReadyForCommand",
+	message.RestoreReady:           "RestoreReady",
+	message.ServerHandshake:        "ServerHandshake",
+	message.ServerKeyData:          "ServerKeyData",
+	message.StateDataDescription:   "StateDataDescription",
+}
+
+type fieldAdder func(format string, args ...interface{})
+
+func describeClientMessage(r *buff.Reader, name string, add fieldAdder) {
+	switch name {
+	case "ClientHandshake":
+		add("version=%v.%v", r.PopUint16(), r.PopUint16())
+		n := int(r.PopUint16())
+		for i := 0; i < n; i++ {
+			add("%v=%q", r.PopString(), r.PopString())
+		}
+	case "AuthenticationSASLInitialResponse":
+		add("method=%q", r.PopString())
+	case "Prepare":
+		describeHeaders(r, add)
+		add("format=%q", r.PopUint8())
+		add("cardinality=%v", cardinality.ToStr[r.PopUint8()])
+		r.PopBytes() // statement name
+		add("command=%q", r.PopString())
+	case "OptimisticExecute":
+		describeHeaders(r, add)
+		add("format=%q", r.PopUint8())
+		add("cardinality=%v", cardinality.ToStr[r.PopUint8()])
+		add("command=%q", r.PopString())
+		add("input=%v output=%v", r.PopUUID(), r.PopUUID())
+	case "Parse", "Execute":
+		describeHeaders(r, add)
+		add("capabilities=0x%x", r.PopUint64())
+		add("flags=0x%x", r.PopUint64())
+		add("implicit_limit=%v", r.PopUint64())
+		add("format=%q", r.PopUint8())
+		add("cardinality=%v", cardinality.ToStr[r.PopUint8()])
+		add("command=%q", r.PopString())
+		add("state=%v", r.PopUUID())
+		r.PopBytes() // state data
+
+		if name == "Execute" {
+			add("input=%v output=%v", r.PopUUID(), r.PopUUID())
+		}
+	case "DescribeStatement":
+		describeHeaders(r, add)
+		add("aspect=%q", r.PopUint8())
+	case "Execute0pX":
+		describeHeaders(r, add)
+		r.PopBytes() // statement name
+		add("arguments=%v", len(r.Buf))
+	case "ExecuteScript":
+		describeHeaders(r, add)
+		add("script=%q", r.PopString())
+	}
+}
+
+func describeServerMessage(
+	r *buff.Reader,
+	name string,
+	v version,
+	add fieldAdder,
+) {
+	switch name {
+	case "ServerHandshake":
+		add("version=%v.%v", r.PopUint16(), r.PopUint16())
+	case "Authentication":
+		status := r.PopUint32()
+		add("status=0x%x", status)
+		if status == 0xa {
+			n := int(r.PopUint32())
+			for i := 0; i < n; i++ {
+				add("method=%q", r.PopString())
+			}
+		}
+	case "ParameterStatus":
+		add("name=%q", r.PopString())
+	case "ReadyForCommand":
+		describeHeaders(r, add)
+		add("transaction_state=%q", r.PopUint8())
+	case "CommandComplete":
+		describeHeaders(r, add)
+		if v.gte(protocolVersion1p0) {
+			add("capabilities=0x%x", r.PopUint64())
+		}
+		add("status=%q", r.PopString())
+	case "CommandDataDescription":
+		describeHeaders(r, add)
+		if v.gte(protocolVersion1p0) {
+			add("capabilities=0x%x", r.PopUint64())
+		}
+		add("cardinality=%v", cardinality.ToStr[r.PopUint8()])
+		add("input=%v", r.PopUUID())
+		r.PopBytes() // input descriptor
+		add("output=%v", r.PopUUID())
+	case "PrepareComplete":
+		describeHeaders(r, add)
+		add("cardinality=%v", cardinality.ToStr[r.PopUint8()])
+		add("input=%v output=%v", r.PopUUID(), r.PopUUID())
+	case "Data":
+		add("elements=%v", r.PopUint16())
+		add("length=%v", r.PopUint32())
+	case "ErrorResponse", "LogMessage":
+		add("severity=0x%x", r.PopUint8())
+		add("code=0x%x", r.PopUint32())
+		add("message=%q", r.PopString())
+	case "StateDataDescription":
+		add("id=%v", r.PopUUID())
+	}
+}
+
+// describeHeaders adds the number of headers if there are any.
+func describeHeaders(r *buff.Reader, add fieldAdder) {
+	n := int(r.PopUint16())
+	for i := 0; i < n; i++ {
+		r.Discard(2) // header key
+		r.PopBytes() // header value
+	}
+
+	if n > 0 {
+		add("headers=%v", n)
+	}
+}
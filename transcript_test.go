@@ -0,0 +1,209 @@
+// This source file is part of the EdgeDB open source project.
+//
+// Copyright 2020-present EdgeDB Inc. and the EdgeDB authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package edgedb
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/edgedb/edgedb-go/edgedbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordSession runs a query against a fake server
+// and returns the transcript of the session.
+func recordSession(t *testing.T, query string) string {
+	server, err := edgedbtest.NewServer()
+	require.Nil(t, err)
+	defer server.Close() // nolint:errcheck
+
+	server.Handle(query, edgedbtest.Response{
+		Type: edgedbtest.Int64,
+		Rows: []interface{}{int64(1), int64(2)},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var transcript bytes.Buffer
+	p, err := Connect(ctx, Options{
+		Hosts:      []string{server.Host()},
+		Ports:      []int{server.Port()},
+		User:       "edgedb",
+		Database:   "edgedb",
+		MinConns:   1,
+		MaxConns:   1,
+		Transcript: &transcript,
+	})
+	require.Nil(t, err)
+
+	var result []int64
+	require.Nil(t, p.Query(ctx, query, &result))
+	assert.Equal(t, []int64{1, 2}, result)
+	require.Nil(t, p.Close())
+
+	return transcript.String()
+}
+
+func replayOptions(t *testing.T, transcript string) Options {
+	dial, err := ReplayDialer(strings.NewReader(transcript))
+	require.Nil(t, err)
+
+	return Options{
+		Hosts:    []string{"replay"},
+		User:     "edgedb",
+		Database: "edgedb",
+		MinConns: 1,
+		MaxConns: 1,
+		Dialer:   dial,
+	}
+}
+
+func TestTranscriptRecordAndReplay(t *testing.T) {
+	query := "SELECT {1, 2}"
+	transcript := recordSession(t, query)
+
+	for _, expected := range []string{
+		" open tcp 127.0.0.1:",
+		" C ClientHandshake version=1.0 ",
+		`database="edgedb" user="edgedb"`,
+		" S ServerHandshake version=0.13",
+		" C Prepare ",
+		`command="SELECT {1, 2}"`,
+		" S Data elements=1 length=8",
+		" S ReadyForCommand transaction_state='I'",
+		" C Terminate",
+		" close",
+	} {
+		assert.Contains(t, transcript, expected)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p, err := Connect(ctx, replayOptions(t, transcript))
+	require.Nil(t, err)
+
+	var result []int64
+	require.Nil(t, p.Query(ctx, query, &result))
+	assert.Equal(t, []int64{1, 2}, result)
+	require.Nil(t, p.Close())
+}
+
+func TestReplayMismatch(t *testing.T) {
+	transcript := recordSession(t, "SELECT {1, 2}")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p, err := Connect(ctx, replayOptions(t, transcript))
+	require.Nil(t, err)
+	defer p.Close() // nolint:errcheck
+
+	// the transcript has a Prepare message, not ExecuteScript.
+	err = p.Execute(ctx, "SELECT 1")
+
+	var edbErr Error
+	require.True(t, errors.As(err, &edbErr), err)
+	assert.True(t, edbErr.Category(ProtocolError), err)
+	assert.Contains(t, err.Error(), "replay failed")
+}
+
+func TestReplayDialerRunsOutOfConnections(t *testing.T) {
+	dial, err := ReplayDialer(strings.NewReader(""))
+	require.Nil(t, err)
+
+	_, err = dial(context.Background(), "tcp", "localhost:5656")
+	assert.EqualError(
+		t,
+		err,
+		"edgedb.ClientConnectionFailedError: "+
+			"no more connections in the transcript",
+	)
+}
+
+func TestParseTranscriptErrors(t *testing.T) {
+	tests := []struct {
+		transcript string
+		err        string
+	}{
+		{"1", "invalid transcript on line 1: too few fields"},
+		{"1 C 5600000004", "invalid transcript on line 1: " +
+			"connection not open"},
+		{"1 open tcp a:1\n1 open tcp a:1", "invalid transcript on line 2: " +
+			"connection reopened"},
+		{"1 open tcp a:1\n\n# comment\n1 S 5600000005", "invalid " +
+			"transcript on line 4: malformed message"},
+		{"1 open tcp a:1\n1 S zz", "invalid transcript on line 2: " +
+			"malformed message"},
+		{"1 reopen", `invalid transcript on line 1: unknown event "reopen"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.transcript, func(t *testing.T) {
+			_, err := ReplayDialer(strings.NewReader(test.transcript))
+			assert.EqualError(t, err, "edgedb.InterfaceError: "+test.err)
+		})
+	}
+}
+
+func TestTranscriptRedactsAuthentication(t *testing.T) {
+	server, err := edgedbtest.NewServer()
+	require.Nil(t, err)
+	defer server.Close() // nolint:errcheck
+
+	server.SetPassword("edgedb", "secret")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var transcript bytes.Buffer
+	p, err := Connect(ctx, Options{
+		Hosts:      []string{server.Host()},
+		Ports:      []int{server.Port()},
+		User:       "edgedb",
+		Password:   "secret",
+		Database:   "edgedb",
+		MinConns:   1,
+		MaxConns:   1,
+		Transcript: &transcript,
+	})
+	require.Nil(t, err)
+	require.Nil(t, p.Close())
+
+	for _, expected := range []string{
+		` C AuthenticationSASLInitialResponse method="SCRAM-SHA-256"`,
+		" C AuthenticationSASLResponse",
+		" S Authentication status=0xb",
+		" S Authentication status=0xc",
+		" S ServerKeyData",
+	} {
+		assert.Contains(t, transcript.String(), expected)
+	}
+
+	// the SCRAM messages are redacted.
+	for _, secret := range []string{"n=edgedb,r=", ",i=4096", "c=biws"} {
+		encoded := hex.EncodeToString([]byte(secret))
+		assert.NotContains(t, transcript.String(), encoded)
+	}
+}
@@ -38,6 +38,60 @@ type (
 
 	// RelativeDuration represents a fuzzy/human span of time.
 	RelativeDuration = edgedbtypes.RelativeDuration
+
+	// Decimal is an exact decimal number.
+	Decimal = edgedbtypes.Decimal
+
+	// OptionalBool is a bool that may be missing.
+	OptionalBool = edgedbtypes.OptionalBool
+
+	// OptionalBytes is a []byte that may be missing.
+	OptionalBytes = edgedbtypes.OptionalBytes
+
+	// OptionalStr is a string that may be missing.
+	OptionalStr = edgedbtypes.OptionalStr
+
+	// OptionalInt16 is an int16 that may be missing.
+	OptionalInt16 = edgedbtypes.OptionalInt16
+
+	// OptionalInt32 is an int32 that may be missing.
+	OptionalInt32 = edgedbtypes.OptionalInt32
+
+	// OptionalInt64 is an int64 that may be missing.
+	OptionalInt64 = edgedbtypes.OptionalInt64
+
+	// OptionalFloat32 is a float32 that may be missing.
+	OptionalFloat32 = edgedbtypes.OptionalFloat32
+
+	// OptionalFloat64 is a float64 that may be missing.
+	OptionalFloat64 = edgedbtypes.OptionalFloat64
+
+	// OptionalUUID is a UUID that may be missing.
+	OptionalUUID = edgedbtypes.OptionalUUID
+
+	// OptionalDateTime is a time.Time that may be missing.
+	OptionalDateTime = edgedbtypes.OptionalDateTime
+
+	// OptionalLocalDateTime is a LocalDateTime that may be missing.
+	OptionalLocalDateTime = edgedbtypes.OptionalLocalDateTime
+
+	// OptionalLocalDate is a LocalDate that may be missing.
+	OptionalLocalDate = edgedbtypes.OptionalLocalDate
+
+	// OptionalLocalTime is a LocalTime that may be missing.
+	OptionalLocalTime = edgedbtypes.OptionalLocalTime
+
+	// OptionalDuration is a Duration that may be missing.
+	OptionalDuration = edgedbtypes.OptionalDuration
+
+	// OptionalRelativeDuration is a RelativeDuration that may be missing.
+	OptionalRelativeDuration = edgedbtypes.OptionalRelativeDuration
+
+	// OptionalBigInt is a *big.Int that may be missing.
+	OptionalBigInt = edgedbtypes.OptionalBigInt
+
+	// OptionalDecimal is a Decimal that may be missing.
+	OptionalDecimal = edgedbtypes.OptionalDecimal
 )
 
 var (
@@ -55,4 +109,66 @@ var (
 
 	// NewRelativeDuration returns a new RelativeDuration
 	NewRelativeDuration = edgedbtypes.NewRelativeDuration
+
+	// NewDecimal returns the Decimal unscaled * 10^-scale.
+	NewDecimal = edgedbtypes.NewDecimal
+
+	// NewDecimalFromFloat returns the shortest Decimal
+	// that rounds to f at f's precision.
+	NewDecimalFromFloat = edgedbtypes.NewDecimalFromFloat
+
+	// ParseDecimal parses s into a Decimal or returns an error.
+	ParseDecimal = edgedbtypes.ParseDecimal
+
+	// NewOptionalBool returns an OptionalBool set to v.
+	NewOptionalBool = edgedbtypes.NewOptionalBool
+
+	// NewOptionalBytes returns an OptionalBytes set to v.
+	NewOptionalBytes = edgedbtypes.NewOptionalBytes
+
+	// NewOptionalStr returns an OptionalStr set to v.
+	NewOptionalStr = edgedbtypes.NewOptionalStr
+
+	// NewOptionalInt16 returns an OptionalInt16 set to v.
+	NewOptionalInt16 = edgedbtypes.NewOptionalInt16
+
+	// NewOptionalInt32 returns an OptionalInt32 set to v.
+	NewOptionalInt32 = edgedbtypes.NewOptionalInt32
+
+	// NewOptionalInt64 returns an OptionalInt64 set to v.
+	NewOptionalInt64 = edgedbtypes.NewOptionalInt64
+
+	// NewOptionalFloat32 returns an OptionalFloat32 set to v.
+	NewOptionalFloat32 = edgedbtypes.NewOptionalFloat32
+
+	// NewOptionalFloat64 returns an OptionalFloat64 set to v.
+	NewOptionalFloat64 = edgedbtypes.NewOptionalFloat64
+
+	// NewOptionalUUID returns an OptionalUUID set to v.
+	NewOptionalUUID = edgedbtypes.NewOptionalUUID
+
+	// NewOptionalDateTime returns an OptionalDateTime set to v.
+	NewOptionalDateTime = edgedbtypes.NewOptionalDateTime
+
+	// NewOptionalLocalDateTime returns an OptionalLocalDateTime set to v.
+	NewOptionalLocalDateTime = edgedbtypes.NewOptionalLocalDateTime
+
+	// NewOptionalLocalDate returns an OptionalLocalDate set to v.
+	NewOptionalLocalDate = edgedbtypes.NewOptionalLocalDate
+
+	// NewOptionalLocalTime returns an OptionalLocalTime set to v.
+	NewOptionalLocalTime = edgedbtypes.NewOptionalLocalTime
+
+	// NewOptionalDuration returns an OptionalDuration set to v.
+	NewOptionalDuration = edgedbtypes.NewOptionalDuration
+
+	// NewOptionalRelativeDuration returns an OptionalRelativeDuration
+	// set to v.
+	NewOptionalRelativeDuration = edgedbtypes.NewOptionalRelativeDuration
+
+	// NewOptionalBigInt returns an OptionalBigInt set to v.
+	NewOptionalBigInt = edgedbtypes.NewOptionalBigInt
+
+	// NewOptionalDecimal returns an OptionalDecimal set to v.
+	NewOptionalDecimal = edgedbtypes.NewOptionalDecimal
 )
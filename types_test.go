@@ -1863,6 +1863,94 @@ func TestSendAndReceiveBigIntMarshaler(t *testing.T) {
 	)
 }
 
+func TestSendAndReceiveDecimal(t *testing.T) {
+	ctx := context.Background()
+
+	query := `
+		WITH
+			d := <decimal>$0,
+			s := <str>$1
+		SELECT (
+			encoded := <str>d,
+			decoded := <decimal>s,
+			round_trip := d,
+			is_equal := <decimal>s = d,
+			string := <str><decimal>s,
+		)
+	`
+
+	type Result struct {
+		Encoded   string  `edgedb:"encoded"`
+		Decoded   Decimal `edgedb:"decoded"`
+		RoundTrip Decimal `edgedb:"round_trip"`
+		IsEqual   bool    `edgedb:"is_equal"`
+		String    string  `edgedb:"string"`
+	}
+
+	samples := []string{
+		"0",
+		"1",
+		"-1",
+		"0.1",
+		"-0.1",
+		"0.00",
+		"12.50",
+		"-15000.6250000",
+		"0.0001",
+		"-0.00012345",
+		"9999.9999",
+		"10000.0001",
+		"100000000",
+		"123456789012345678901234567890.123456789",
+	}
+
+	// Generate random decimals
+	for i := 0; i < 1000; i++ {
+		n := rand.Intn(30) + 1
+		num := make([]byte, n)
+
+		for j := 0; j < n; j++ {
+			num[j] = "0123456789"[rand.Intn(10)]
+		}
+
+		// split the digits at a random decimal point
+		k := rand.Intn(n + 1)
+		s := strings.TrimLeft(string(num[:k]), "0")
+		if s == "" {
+			s = "0"
+		}
+
+		if k < n {
+			s += "." + string(num[k:])
+		}
+
+		// 33% chance for a negative number
+		if rand.Intn(3) == 0 && strings.Trim(s, "0.") != "" {
+			s = "-" + s
+		}
+
+		samples = append(samples, s)
+	}
+
+	for _, s := range samples {
+		t.Run(s, func(t *testing.T) {
+			d, err := ParseDecimal(s)
+			require.Nil(t, err)
+			require.Equal(t, s, d.String())
+
+			var result Result
+			err = conn.QueryOne(ctx, query, &result, d, s)
+			assert.Nil(t, err, "unexpected error: %v", err)
+
+			assert.True(t, result.IsEqual, "equality check faild")
+			assert.Equal(t, s, result.Encoded, "encoding failed")
+			assert.Equal(t, d, result.Decoded)
+			assert.Equal(t, d, result.RoundTrip)
+			assert.Equal(t, s, result.String)
+		})
+	}
+}
+
 type CustomDecimal struct {
 	data []byte
 }
@@ -2337,3 +2425,51 @@ func TestReceiveSet(t *testing.T) {
 		)
 	}
 }
+
+func TestSendAndReceiveOptional(t *testing.T) {
+	ctx := context.Background()
+
+	query := `SELECT (
+		str := <OPTIONAL str>$0,
+		int64 := <OPTIONAL int64>$1,
+		ptr := <OPTIONAL str>$0,
+		is_empty := NOT EXISTS <OPTIONAL int64>$1,
+	)`
+
+	type Result struct {
+		Str     OptionalStr   `edgedb:"str"`
+		Int64   OptionalInt64 `edgedb:"int64"`
+		Ptr     *string       `edgedb:"ptr"`
+		IsEmpty bool          `edgedb:"is_empty"`
+	}
+
+	var result Result
+	err := conn.QueryOne(
+		ctx, query, &result, NewOptionalStr("hello"), OptionalInt64{},
+	)
+	require.Nil(t, err, "unexpected error: %v", err)
+
+	str, ok := result.Str.Get()
+	assert.True(t, ok)
+	assert.Equal(t, "hello", str)
+
+	_, ok = result.Int64.Get()
+	assert.False(t, ok)
+	require.NotNil(t, result.Ptr)
+	assert.Equal(t, "hello", *result.Ptr)
+	assert.True(t, result.IsEmpty)
+
+	err = conn.QueryOne(
+		ctx, query, &result, OptionalStr{}, NewOptionalInt64(7),
+	)
+	require.Nil(t, err, "unexpected error: %v", err)
+
+	_, ok = result.Str.Get()
+	assert.False(t, ok)
+
+	i, ok := result.Int64.Get()
+	assert.True(t, ok)
+	assert.Equal(t, int64(7), i)
+	assert.Nil(t, result.Ptr)
+	assert.False(t, result.IsEmpty)
+}